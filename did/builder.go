@@ -0,0 +1,196 @@
+package did
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DocumentBuilder assembles a DID document step by step:
+//
+//	doc, err := NewDocumentBuilder(did).
+//		AddKey(did.String()+"#key-1", key, RelationshipAuthentication, RelationshipAssertionMethod).
+//		AddAgreementKey(did.String()+"#agreement", agreementKey).
+//		AddService(Service{ID: "#didcomm", Type: MessagingDIDType, ServiceEndpoint: "https://msg.mailio.com"}).
+//		Build()
+//
+// Errors are collected along the way and returned by Build, which also validates the document.
+type DocumentBuilder struct {
+	doc        *Document
+	controller string
+	vmType     string
+	errs       []error
+}
+
+// NewDocumentBuilder starts a document of the DID with the CtxDIDv1 context.
+// Methods added by key are controlled by the DID unless SetMethodController says otherwise.
+func NewDocumentBuilder(id DID) *DocumentBuilder {
+	return &DocumentBuilder{
+		doc: &Document{
+			Context: []string{CtxDIDv1},
+			ID:      id,
+		},
+		controller: id.String(),
+		vmType:     PublicKeyJwkType,
+	}
+}
+
+// AddContext appends JSON-LD contexts not yet present in the document
+func (b *DocumentBuilder) AddContext(contexts ...string) *DocumentBuilder {
+	for _, c := range contexts {
+		if !containsString(b.doc.Context, c) {
+			b.doc.Context = append(b.doc.Context, c)
+		}
+	}
+	return b
+}
+
+// AddAlsoKnownAs appends alternative identifiers (URIs) of the DID subject
+func (b *DocumentBuilder) AddAlsoKnownAs(uris ...string) *DocumentBuilder {
+	b.doc.AlsoKnownAs = append(b.doc.AlsoKnownAs, uris...)
+	return b
+}
+
+// AddController adds DIDs to the document controller set
+func (b *DocumentBuilder) AddController(controllers ...string) *DocumentBuilder {
+	for _, c := range controllers {
+		if !containsString(b.doc.Controller, c) {
+			b.doc.Controller = append(b.doc.Controller, c)
+		}
+	}
+	return b
+}
+
+// SetMethodController sets the controller of the methods subsequently added by key
+func (b *DocumentBuilder) SetMethodController(controller string) *DocumentBuilder {
+	b.controller = controller
+	return b
+}
+
+// SetVerificationMethodType sets the type subsequently added keys are published as (see WithVerificationMethodType)
+func (b *DocumentBuilder) SetVerificationMethodType(vmType string) *DocumentBuilder {
+	switch vmType {
+	case PublicKeyJwkType, KeyTypeEd25519, KeyTypeMultikey:
+		b.vmType = vmType
+	default:
+		b.errs = append(b.errs, fmt.Errorf("%w: %s", ErrUnsupportedKeyType, vmType))
+	}
+	return b
+}
+
+// AddVerificationMethod adds the verification method and references it from the relationships
+// (e.g. RelationshipAuthentication)
+func (b *DocumentBuilder) AddVerificationMethod(vm VerificationMethod, relationships ...string) *DocumentBuilder {
+	b.doc.VerificationMethod = append(b.doc.VerificationMethod, vm)
+	if vm.Type == KeyTypeMultikey {
+		b.AddContext(CtxSecMultikeyV1)
+	}
+	for _, r := range relationships {
+		b.AddRelationship(r, NewReferenceRelationship(vm.ID))
+	}
+	return b
+}
+
+// AddKey publishes the key as a verification method with the id and references it from the relationships
+func (b *DocumentBuilder) AddKey(id string, key *Key, relationships ...string) *DocumentBuilder {
+	vm, err := newKeyVerificationMethod(id, b.controller, key, b.vmType)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("key %q: %w", id, err))
+		return b
+	}
+	return b.AddVerificationMethod(vm, relationships...)
+}
+
+// AddEmbeddedKey publishes the key as a verification method embedded in the relationship only
+func (b *DocumentBuilder) AddEmbeddedKey(relationship string, id string, key *Key) *DocumentBuilder {
+	vm, err := newKeyVerificationMethod(id, b.controller, key, b.vmType)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("key %q: %w", id, err))
+		return b
+	}
+	if vm.Type == KeyTypeMultikey {
+		b.AddContext(CtxSecMultikeyV1)
+	}
+	return b.AddRelationship(relationship, NewEmbeddedRelationship(vm))
+}
+
+// AddRelationship adds a reference or an embedded method to the verification relationship
+func (b *DocumentBuilder) AddRelationship(relationship string, r VerificationRelationship) *DocumentBuilder {
+	switch relationship {
+	case RelationshipAuthentication:
+		b.doc.Authentication = append(b.doc.Authentication, r)
+	case RelationshipAssertionMethod:
+		b.doc.AssertionMethod = append(b.doc.AssertionMethod, r)
+	case RelationshipCapabilityInvocation:
+		b.doc.CapabilityInvocation = append(b.doc.CapabilityInvocation, r)
+	case RelationshipCapabilityDelegation:
+		b.doc.CapabilityDelegation = append(b.doc.CapabilityDelegation, r)
+	default:
+		b.errs = append(b.errs, fmt.Errorf("unknown verification relationship %q", relationship))
+	}
+	return b
+}
+
+// AddKeyAgreement adds the key agreement method
+func (b *DocumentBuilder) AddKeyAgreement(ka KeyAgreement) *DocumentBuilder {
+	b.doc.KeyAgreement = append(b.doc.KeyAgreement, ka)
+	return b
+}
+
+// AddAgreementKey publishes the X25519 key as X25519KeyAgreementKey2019 with both publicKeyMultibase and publicKeyJwk
+func (b *DocumentBuilder) AddAgreementKey(id string, key *Key) *DocumentBuilder {
+	ka, err := newX25519KeyAgreement(id, b.controller, key)
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("agreement key %q: %w", id, err))
+		return b
+	}
+	return b.AddKeyAgreement(ka)
+}
+
+// AddService adds the service (e.g. DIDCommMessaging with RoutingKeys)
+func (b *DocumentBuilder) AddService(s Service) *DocumentBuilder {
+	b.doc.Service = append(b.doc.Service, s)
+	return b
+}
+
+// Build returns the document if no errors occurred while building it and it passes Validate
+func (b *DocumentBuilder) Build() (*Document, error) {
+	if len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+	if err := b.doc.Validate(); err != nil {
+		return nil, err
+	}
+	return b.doc, nil
+}
+
+// newX25519KeyAgreement publishes the X25519 key as X25519KeyAgreementKey2019
+func newX25519KeyAgreement(id string, controller string, key *Key) (KeyAgreement, error) {
+	agreementKey, err := x25519PublicKey(key.PublicKey)
+	if err != nil {
+		return KeyAgreement{}, err
+	}
+	agreementJwk, err := x25519PublicKeyToJwk(agreementKey)
+	if err != nil {
+		return KeyAgreement{}, err
+	}
+	agreementMultibase, err := EncodePublicKeyMultibase(agreementKey)
+	if err != nil {
+		return KeyAgreement{}, err
+	}
+	return KeyAgreement{
+		ID:                 id,
+		Type:               KeyTypeX25519KeyAgreement,
+		Controller:         controller,
+		PublicKeyMultibase: agreementMultibase,
+		PublicKeyJwk:       agreementJwk,
+	}, nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
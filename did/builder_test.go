@@ -0,0 +1,81 @@
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDocumentBuilder(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+	other, _ := GenerateMailioPublicKeys()
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	doc, err := NewDocumentBuilder(did).
+		AddContext(CtxDIDCommMsg_v2, CtxDIDv1).
+		AddAlsoKnownAs("https://mail.io/alice").
+		AddKey("#master", mk.MasterSignKey, RelationshipAuthentication, RelationshipCapabilityInvocation).
+		SetVerificationMethodType(KeyTypeMultikey).
+		AddKey("#hsm", &Key{PublicKey: &p256.PublicKey}, RelationshipAssertionMethod).
+		SetMethodController(mailioDID(t, other)).
+		AddKey("#delegate", other.MasterSignKey, RelationshipCapabilityDelegation).
+		AddAgreementKey("#agreement", mk.MasterAgreementKey).
+		AddService(Service{
+			ID:              "#didcomm",
+			Type:            MessagingDIDType,
+			ServiceEndpoint: "https://msg.mailio.com",
+			RoutingKeys:     []string{did.String() + "#agreement"},
+		}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{CtxDIDv1, CtxDIDCommMsg_v2, CtxSecMultikeyV1}, doc.Context)
+	assert.Equal(t, []string{"https://mail.io/alice"}, doc.AlsoKnownAs)
+	assert.Len(t, doc.VerificationMethod, 3)
+	assert.Equal(t, PublicKeyJwkType, doc.VerificationMethod[0].Type)
+	assert.Equal(t, KeyTypeMultikey, doc.VerificationMethod[1].Type)
+	assert.Equal(t, mailioDID(t, other), doc.VerificationMethod[2].Controller)
+
+	_, err = doc.FindRelationshipMethod(RelationshipCapabilityInvocation, "#master")
+	assert.NoError(t, err)
+	_, err = doc.FindAssertionMethod("#hsm")
+	assert.NoError(t, err)
+	_, err = doc.FindRelationshipMethod(RelationshipCapabilityDelegation, did.String()+"#delegate")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{did.String() + "#agreement"}, doc.Service[0].RoutingKeys)
+}
+
+func TestDocumentBuilderErrors(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+
+	_, err := NewDocumentBuilder(did).
+		AddKey("#master", mk.MasterSignKey).
+		AddRelationship("keyRotation", NewReferenceRelationship("#master")).
+		Build()
+	assert.Error(t, err)
+
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err = NewDocumentBuilder(did).
+		AddAgreementKey("#agreement", &Key{PublicKey: &p256.PublicKey}).
+		Build()
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	_, err = NewDocumentBuilder(did).
+		AddKey("#master", &Key{PublicKey: "not a key"}).
+		Build()
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+
+	// the document itself is validated
+	_, err = NewDocumentBuilder(did).
+		AddKey("#master", mk.MasterSignKey).
+		AddKey("#master", mk.MasterSignKey).
+		AddRelationship(RelationshipAuthentication, NewReferenceRelationship("#missing")).
+		Build()
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+}
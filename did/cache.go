@@ -0,0 +1,167 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStats are the counters of a CachingResolver. Lookups coalesced with an in-flight resolution count as hits.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+type cacheEntry struct {
+	doc       *Document
+	resMeta   *ResolutionMetadata
+	docMeta   *DocumentMetadata
+	err       error
+	expiresAt time.Time
+}
+
+// in-flight resolution shared by concurrent lookups of the same DID, done is closed when entry is set
+type cacheCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+}
+
+// CachingResolver wraps a Resolver with a concurrency-safe cache.
+//
+// Documents are cached for ttl or until DocumentMetadata.NextUpdate / ResolutionMetadata.Expires,
+// whichever comes first. notFound results are cached for negativeTTL, other errors are never cached.
+// Concurrent lookups of the same DID are coalesced into a single call of the wrapped resolver,
+// waiting lookups return early with the error of their context when it's done. The call runs with the context
+// of the lookup that started it; if it fails because that context ended, waiting lookups resolve again.
+// Cached documents are shared between callers and must not be modified.
+type CachingResolver struct {
+	resolver    Resolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	calls   map[string]*cacheCall
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewCachingResolver wraps the resolver with a cache
+func NewCachingResolver(resolver Resolver, ttl time.Duration, negativeTTL time.Duration) *CachingResolver {
+	return &CachingResolver{
+		resolver:    resolver,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		now:         time.Now,
+		entries:     make(map[string]*cacheEntry),
+		calls:       make(map[string]*cacheCall),
+	}
+}
+
+func (c *CachingResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	key := "did:" + did.Protocol() + ":" + did.Value()
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if c.now().Before(e.expiresAt) {
+			c.mu.Unlock()
+			c.hits.Add(1)
+			return e.doc, e.resMeta, e.docMeta, e.err
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		c.hits.Add(1)
+		select {
+		case <-call.done:
+			// the lookup failed with the context of the leading caller, resolve again with our own
+			if isContextError(call.entry.err) && ctx.Err() == nil {
+				return c.Resolve(ctx, did)
+			}
+			return call.entry.doc, call.entry.resMeta, call.entry.docMeta, call.entry.err
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		}
+	}
+	call := &cacheCall{
+		done: make(chan struct{}),
+		// replaced by the result unless the wrapped resolver panics
+		entry: &cacheEntry{err: fmt.Errorf("resolver panicked resolving %s", key)},
+	}
+	c.calls[key] = call
+	c.mu.Unlock()
+	c.misses.Add(1)
+
+	// release the waiters even if the wrapped resolver panics
+	defer func() {
+		c.mu.Lock()
+		if expiresAt, cacheable := c.expiry(call.entry); cacheable {
+			call.entry.expiresAt = expiresAt
+			c.entries[key] = call.entry
+		}
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	doc, resMeta, docMeta, err := c.resolver.Resolve(ctx, did)
+	call.entry = &cacheEntry{
+		doc:     doc,
+		resMeta: resMeta,
+		docMeta: docMeta,
+		err:     err,
+	}
+	return doc, resMeta, docMeta, err
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// ResolveVersion resolves historical versions with the wrapped resolver, bypassing the cache.
+// The current version (zero selector) is served from the cache.
+func (c *CachingResolver) ResolveVersion(ctx context.Context, did DID, version VersionSelector) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	if version.IsZero() {
+		return c.Resolve(ctx, did)
+	}
+	return ResolveVersion(ctx, c.resolver, did, version)
+}
+
+func (c *CachingResolver) expiry(e *cacheEntry) (time.Time, bool) {
+	now := c.now()
+	if e.err != nil {
+		if errors.Is(e.err, ErrNotFound) && c.negativeTTL > 0 {
+			return now.Add(c.negativeTTL), true
+		}
+		return time.Time{}, false
+	}
+	expiresAt := now.Add(c.ttl)
+	if e.docMeta != nil && e.docMeta.NextUpdate != nil && e.docMeta.NextUpdate.Before(expiresAt) {
+		expiresAt = *e.docMeta.NextUpdate
+	}
+	if e.resMeta != nil && e.resMeta.Expires != nil && e.resMeta.Expires.Before(expiresAt) {
+		expiresAt = *e.resMeta.Expires
+	}
+	return expiresAt, expiresAt.After(now)
+}
+
+// Invalidate removes the cached resolution result of the DID
+func (c *CachingResolver) Invalidate(did DID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, "did:"+did.Protocol()+":"+did.Value())
+}
+
+// Stats returns the hit and miss counters
+func (c *CachingResolver) Stats() CacheStats {
+	return CacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+}
@@ -0,0 +1,172 @@
+package did
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingResolver(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	inner := ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		calls.Add(1)
+		<-release
+		if did.Value() != doc.ID.Value() {
+			return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, ErrNotFound
+		}
+		return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+	})
+	cache := NewCachingResolver(inner, time.Minute, time.Second)
+
+	// concurrent lookups are coalesced into a single resolution
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resolved, _, _, err := cache.Resolve(context.Background(), doc.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, doc.ID.String(), resolved.ID.String())
+		}()
+	}
+	for cache.Stats().Hits+cache.Stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, CacheStats{Hits: 9, Misses: 1}, cache.Stats())
+
+	// notFound is cached for the negative TTL
+	missing, _ := ParseDID("did:mailio:0x0000000000000000000000000000000000000000")
+	_, _, _, err := cache.Resolve(context.Background(), missing)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, _, err = cache.Resolve(context.Background(), missing)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, int32(2), calls.Load())
+
+	now := time.Now()
+	cache.now = func() time.Time { return now.Add(2 * time.Second) }
+	cache.Resolve(context.Background(), missing)
+	assert.Equal(t, int32(3), calls.Load())
+	cache.Resolve(context.Background(), doc.ID)
+	assert.Equal(t, int32(3), calls.Load())
+
+	cache.Invalidate(doc.ID)
+	cache.Resolve(context.Background(), doc.ID)
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+func TestHTTPCacheExpiry(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=60")
+	assert.Equal(t, now.Add(time.Minute), *httpCacheExpiry(header, now))
+
+	header.Set("Cache-Control", "no-store")
+	assert.Equal(t, now, *httpCacheExpiry(header, now))
+
+	assert.Nil(t, httpCacheExpiry(http.Header{}, now))
+}
+
+func TestCachingResolverPanicAndCancel(t *testing.T) {
+	did, _ := ParseDID("did:mailio:0x0000000000000000000000000000000000000000")
+	started := make(chan struct{})
+	release := make(chan struct{})
+	inner := ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		close(started)
+		<-release
+		panic("resolver failure")
+	})
+	cache := NewCachingResolver(inner, time.Minute, time.Minute)
+
+	leaderDone := make(chan interface{})
+	go func() {
+		defer func() { leaderDone <- recover() }()
+		cache.Resolve(context.Background(), did)
+	}()
+	<-started
+
+	// a waiter gives up with its context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, _, err := cache.Resolve(ctx, did)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// a waiter sharing the panicking call gets an error instead of blocking forever
+	waiterErr := make(chan error)
+	go func() {
+		_, _, _, err := cache.Resolve(context.Background(), did)
+		waiterErr <- err
+	}()
+	for cache.Stats().Hits < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	assert.Equal(t, "resolver failure", <-leaderDone)
+	assert.Error(t, <-waiterErr)
+
+	// the failed call isn't cached, later lookups resolve again
+	calls := 0
+	cache.resolver = ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		calls++
+		return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, ErrNotFound
+	})
+	_, _, _, err = cache.Resolve(context.Background(), did)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCachingResolverLeaderCancel(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	var calls atomic.Int32
+	started := make(chan struct{})
+	inner := ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return nil, nil, nil, ctx.Err()
+		}
+		return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+	})
+	cache := NewCachingResolver(inner, time.Minute, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, _, _, err := cache.Resolve(ctx, doc.ID)
+		leaderErr <- err
+	}()
+	<-started
+
+	waiterErr := make(chan error)
+	go func() {
+		resolved, _, _, err := cache.Resolve(context.Background(), doc.ID)
+		if err == nil && resolved.ID.String() != doc.ID.String() {
+			t.Errorf("unexpected document %s", resolved.ID.String())
+		}
+		waiterErr <- err
+	}()
+	for cache.Stats().Hits < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the leader gives up, the waiter with a live context still gets the document
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	assert.NoError(t, <-waiterErr)
+	assert.Equal(t, int32(2), calls.Load())
+}
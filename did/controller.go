@@ -0,0 +1,136 @@
+package did
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maximum number of controller documents walked by AuthorizeProof
+const maxControllerDepth = 8
+
+// ErrUnauthorized is returned when a verification method is not authorized to act for the DID
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ControllerSet is the document controller property (https://www.w3.org/TR/did-core/#did-controller)
+// which is either a single DID or a set of DIDs. A single controller is marshalled as a plain string.
+type ControllerSet []string
+
+func (c ControllerSet) MarshalJSON() ([]byte, error) {
+	if len(c) == 1 {
+		return json.Marshal(c[0])
+	}
+	return json.Marshal([]string(c))
+}
+
+func (c *ControllerSet) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var controller string
+		if err := json.Unmarshal(b, &controller); err != nil {
+			return err
+		}
+		*c = ControllerSet{controller}
+		return nil
+	}
+	var controllers []string
+	if err := json.Unmarshal(b, &controllers); err != nil {
+		return fmt.Errorf("controller must be a DID or a set of DIDs: %w", err)
+	}
+	*c = controllers
+	return nil
+}
+
+// Controllers returns the DIDs allowed to act for the document. Without a controller property
+// the DID subject controls its own document.
+func (d *Document) Controllers() []string {
+	if len(d.Controller) == 0 {
+		return []string{"did:" + d.ID.Protocol() + ":" + d.ID.Value()}
+	}
+	return d.Controller
+}
+
+// AuthorizeProof decides whether the verification method of the proof may act for the document with the proof
+// purpose (e.g. RelationshipCapabilityInvocation to update it). The method must belong to one of the document
+// controllers, directly or through the controllers of controller documents (an organization controlling the
+// mailbox through its own controller), and be listed in the proof purpose relationship of its own document.
+// The document itself and its controller documents are resolved with the resolver, so a stale copy of the document
+// doesn't authorize keys it no longer lists. Deactivated controllers no longer authorize anything, a proof for a
+// deactivated document or by the method of a deactivated DID fails with ErrDeactivated.
+// The returned verification method holds the key the proof signature must be verified with.
+func AuthorizeProof(ctx context.Context, resolver Resolver, doc *Document, proof *Proof) (*VerificationMethod, error) {
+	if proof == nil || proof.VerificationMethod == "" {
+		return nil, fmt.Errorf("%w: proof without verification method", ErrUnauthorized)
+	}
+	methodDID, _, _ := strings.Cut(doc.AbsoluteID(proof.VerificationMethod), "#")
+
+	self := "did:" + doc.ID.Protocol() + ":" + doc.ID.Value()
+	// the document may be stale, the current version decides (unpublished documents authorize themselves)
+	current, err := resolveController(ctx, resolver, self)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		doc = current
+	}
+	visited := map[string]bool{self: true}
+	controllers := doc.Controllers()
+	for depth := 0; depth < maxControllerDepth && len(controllers) > 0; depth++ {
+		var next []string
+		for _, controller := range controllers {
+			controllerDoc := doc
+			if controller != self {
+				if visited[controller] {
+					continue
+				}
+				visited[controller] = true
+				resolved, err := resolveController(ctx, resolver, controller)
+				if errors.Is(err, ErrDeactivated) && controller != methodDID {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				if resolved == nil {
+					continue
+				}
+				controllerDoc = resolved
+			}
+			if controller == methodDID {
+				vm, err := controllerDoc.FindRelationshipMethod(proof.ProofPurpose, proof.VerificationMethod)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s is not authorized for %s: %v", ErrUnauthorized, proof.VerificationMethod, proof.ProofPurpose, err)
+				}
+				return vm, nil
+			}
+			if controllerDoc != doc {
+				next = append(next, controllerDoc.Controllers()...)
+			}
+		}
+		controllers = next
+	}
+	return nil, fmt.Errorf("%w: %s does not control %s", ErrUnauthorized, methodDID, self)
+}
+
+// resolveController resolves the controller document. Unknown controllers are skipped (nil document),
+// deactivated controllers fail with ErrDeactivated.
+func resolveController(ctx context.Context, resolver Resolver, controller string) (*Document, error) {
+	did, err := ParseDID(controller)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid controller %q", ErrUnauthorized, controller)
+	}
+	doc, _, md, err := resolver.Resolve(ctx, did)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if md != nil && md.Deactivated {
+		return nil, fmt.Errorf("%w: %w: %s", ErrUnauthorized, ErrDeactivated, controller)
+	}
+	return doc, nil
+}
@@ -0,0 +1,76 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControllerSetJSON(t *testing.T) {
+	var c ControllerSet
+	assert.NoError(t, json.Unmarshal([]byte(`"did:mailio:0x1"`), &c))
+	assert.Equal(t, ControllerSet{"did:mailio:0x1"}, c)
+	b, _ := json.Marshal(c)
+	assert.Equal(t, `"did:mailio:0x1"`, string(b))
+
+	assert.NoError(t, json.Unmarshal([]byte(`["did:mailio:0x1","did:web:mail.io"]`), &c))
+	b, _ = json.Marshal(c)
+	assert.Equal(t, `["did:mailio:0x1","did:web:mail.io"]`, string(b))
+	assert.Error(t, json.Unmarshal([]byte(`1`), &c))
+}
+
+func TestAuthorizeProof(t *testing.T) {
+	store := NewMemoryDocumentStore()
+	resolver := NewMailioResolver(store)
+	newDoc := func(controllers ...string) (*MailioKey, *Document) {
+		mk, _ := GenerateMailioPublicKeys()
+		did, _ := mk.DIDFromKey()
+		doc, err := NewDocumentBuilder(did).
+			AddController(controllers...).
+			AddKey(did.String()+MasterKeyFragment, mk.MasterSignKey, RelationshipAuthentication, RelationshipCapabilityInvocation).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+		return mk, doc
+	}
+
+	// organization -> team -> mailbox
+	orgMk, org := newDoc()
+	teamMk, team := newDoc(org.ID.String())
+	mailboxMk, mailbox := newDoc(team.ID.String(), org.ID.String())
+	outsiderMk, _ := newDoc()
+
+	b, _ := json.Marshal(team)
+	assert.Contains(t, string(b), `"controller":"`+org.ID.String()+`"`)
+
+	proof := func(mk *MailioKey, purpose string) *Proof {
+		return &Proof{VerificationMethod: mailioDID(t, mk) + MasterKeyFragment, ProofPurpose: purpose}
+	}
+	ctx := context.Background()
+
+	vm, err := AuthorizeProof(ctx, resolver, mailbox, proof(teamMk, RelationshipCapabilityInvocation))
+	assert.NoError(t, err)
+	assert.Equal(t, mailioDID(t, teamMk)+MasterKeyFragment, vm.ID)
+	_, err = AuthorizeProof(ctx, resolver, mailbox, proof(orgMk, RelationshipCapabilityInvocation))
+	assert.NoError(t, err)
+	_, err = AuthorizeProof(ctx, resolver, team, proof(orgMk, RelationshipCapabilityInvocation))
+	assert.NoError(t, err)
+
+	// a self controlled document
+	_, err = AuthorizeProof(ctx, resolver, org, proof(orgMk, RelationshipCapabilityInvocation))
+	assert.NoError(t, err)
+
+	// controllers replace the DID subject as controller
+	_, err = AuthorizeProof(ctx, resolver, mailbox, proof(mailboxMk, RelationshipCapabilityInvocation))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = AuthorizeProof(ctx, resolver, mailbox, proof(outsiderMk, RelationshipCapabilityInvocation))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = AuthorizeProof(ctx, resolver, team, proof(orgMk, RelationshipCapabilityDelegation))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,108 @@
+package did
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// DereferenceResult holds the resource a DID URL points to. Exactly one of
+// VerificationMethod, KeyAgreement, Service or ServiceEndpoint is set when the DID URL
+// addresses a resource inside the document, otherwise only Document is set.
+type DereferenceResult struct {
+	Document           *Document
+	DocumentMetadata   *DocumentMetadata
+	VerificationMethod *VerificationMethod
+	KeyAgreement       *KeyAgreement
+	Service            *Service
+	// ServiceEndpoint is the concrete endpoint URL selected by the "service" (and optional "relativeRef") DID parameters
+	ServiceEndpoint string
+}
+
+// Dereference resolves the DID of the DID URL and returns the resource it addresses:
+//
+//	did:mailio:0x...#master                       -> verification method
+//	did:mailio:0x...#auth-1                       -> verification method embedded in a verification relationship
+//	did:mailio:0x...?service=didcomm              -> service endpoint of the "didcomm" service
+//	did:mailio:0x...?service=didcomm&relativeRef=/inbox -> service endpoint with the relative reference applied
+//	did:mailio:0x...?versionId=z...#master        -> verification method of a historical document version
+func Dereference(ctx context.Context, resolver Resolver, didURL string) (*DereferenceResult, error) {
+	u, err := ParseDIDURL(didURL)
+	if err != nil {
+		return nil, err
+	}
+	doc, _, docMeta, err := ResolveVersion(ctx, resolver, u.DID, u.Version())
+	if err != nil {
+		return nil, err
+	}
+	result := &DereferenceResult{
+		Document:         doc,
+		DocumentMetadata: docMeta,
+	}
+
+	if service := u.Service(); service != "" {
+		s, sErr := findServiceByName(doc, service)
+		if sErr != nil {
+			return nil, sErr
+		}
+		endpoint, eErr := serviceEndpointURL(s.ServiceEndpoint, u.RelativeRef(), u.Fragment)
+		if eErr != nil {
+			return nil, eErr
+		}
+		result.Service = s
+		result.ServiceEndpoint = endpoint
+		return result, nil
+	}
+
+	if u.Fragment == "" {
+		return result, nil
+	}
+
+	id := "#" + u.Fragment
+	if vm, vmErr := doc.FindVerificationMethod(id); vmErr == nil {
+		result.VerificationMethod = vm
+		return result, nil
+	}
+	if vm, vmErr := doc.findEmbeddedMethod(id); vmErr == nil {
+		result.VerificationMethod = vm
+		return result, nil
+	}
+	if ka, kaErr := doc.FindKeyAgreement(id); kaErr == nil {
+		result.KeyAgreement = ka
+		return result, nil
+	}
+	if s, sErr := doc.FindService(id); sErr == nil {
+		result.Service = s
+		return result, nil
+	}
+	return nil, newResolutionError(ErrCodeNotFound, "%s", didURL)
+}
+
+// findServiceByName matches the "service" DID parameter against the fragment of service ids
+func findServiceByName(doc *Document, name string) (*Service, error) {
+	for i := range doc.Service {
+		id := doc.Service[i].ID
+		if idx := strings.LastIndexByte(id, '#'); idx >= 0 && id[idx+1:] == name {
+			return &doc.Service[i], nil
+		}
+	}
+	return nil, newResolutionError(ErrCodeNotFound, "no service %q", name)
+}
+
+func serviceEndpointURL(endpoint string, relativeRef string, fragment string) (string, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", newResolutionError(ErrCodeNotFound, "invalid service endpoint %q: %v", endpoint, err)
+	}
+	if relativeRef != "" {
+		ref, refErr := url.Parse(relativeRef)
+		if refErr != nil {
+			return "", newResolutionError(ErrCodeInvalidDID, "invalid relativeRef %q: %v", relativeRef, refErr)
+		}
+		base = base.ResolveReference(ref)
+	}
+	if fragment != "" {
+		base.Fragment = fragment
+	}
+	return base.String(), nil
+}
@@ -0,0 +1,63 @@
+package did
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func staticResolver(doc *Document) Resolver {
+	return ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+	})
+}
+
+func TestDereference(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	authPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	mk.AuthenticationKeys = []*Key{{PublicKey: authPub}}
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint+"/api/v2/didmessage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := staticResolver(doc)
+
+	res, err := Dereference(context.Background(), resolver, mailioDID(t, mk)+"#master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mailioDID(t, mk)+"#master", res.VerificationMethod.ID)
+
+	// methods embedded in verification relationships
+	res, err = Dereference(context.Background(), resolver, mailioDID(t, mk)+"#auth-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, mailioDID(t, mk)+"#auth-1", res.VerificationMethod.ID)
+
+	res, err = Dereference(context.Background(), resolver, mailioDID(t, mk)+"?service=didcomm&relativeRef=/inbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, MessagingDIDType, res.Service.Type)
+	assert.Equal(t, MessageServiceEndpoint+"/inbox", res.ServiceEndpoint)
+
+	_, err = Dereference(context.Background(), resolver, mailioDID(t, mk)+"#missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetVerificationPublicKeyEmptyID(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	_, err := doc.GetVerificationPublicKey("")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = doc.GetVerificationPublicKey("#master")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,112 @@
+package did
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+const (
+	DIDMethodJWK = "jwk"
+
+	// JWK "use" parameter values
+	JwkUseSignature  = "sig"
+	JwkUseEncryption = "enc"
+)
+
+// DIDJWKFromKey encodes the public part of the JWK as a did:jwk (https://github.com/quartzjer/did-jwk/blob/main/spec.md)
+func DIDJWKFromKey(key jwk.Key) (DID, error) {
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return DID{}, err
+	}
+	b, err := json.Marshal(publicKey)
+	if err != nil {
+		return DID{}, err
+	}
+	return ParseDID("did:jwk:" + base64.RawURLEncoding.EncodeToString(b))
+}
+
+// NewDIDJWKDocument generates the document of a did:jwk. The key is the verification method "#0", the verification
+// relationships referencing it depend on the "use" parameter of the JWK: "sig" keys are only used for signatures,
+// "enc" keys only for key agreement and keys without "use" for whatever the key algorithm supports.
+func NewDIDJWKDocument(did DID) (*Document, error) {
+	if did.Protocol() != DIDMethodJWK {
+		return nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:jwk: %s", did.String())
+	}
+	b, err := base64.RawURLEncoding.DecodeString(did.Value())
+	if err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:jwk encoding: %v", err)
+	}
+	pk := &PublicKeyJwk{}
+	if err := pk.UnmarshalJSON(b); err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:jwk key: %v", err)
+	}
+	switch pk.Key.(type) {
+	case jwk.OKPPrivateKey, jwk.ECDSAPrivateKey, jwk.RSAPrivateKey, jwk.SymmetricKey:
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:jwk must contain a public key")
+	}
+
+	id := "did:" + did.Protocol() + ":" + did.Value()
+	base, err := ParseDID(id)
+	if err != nil {
+		return nil, err
+	}
+	vmID := id + "#0"
+	doc := &Document{
+		Context: []string{
+			CtxDIDv1,
+			CtxSecJWS2020v1,
+		},
+		ID: base,
+	}
+
+	doc.VerificationMethod = []VerificationMethod{
+		{
+			ID:           vmID,
+			Type:         PublicKeyJwkType,
+			Controller:   id,
+			PublicKeyJwk: pk,
+		},
+	}
+
+	// X25519 keys can't sign, only X25519 and EC keys can be used for key agreement
+	raw, _ := pk.GetRawKey()
+	_, isEC := raw.(*ecdsa.PublicKey)
+	canSign := !isX25519Key(raw)
+	canAgree := isEC || isX25519Key(raw)
+
+	use := pk.Key.KeyUsage()
+	if use == JwkUseEncryption && !canAgree {
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:jwk encryption key %s can't be used for key agreement", pk.Key.KeyType())
+	}
+	if use != JwkUseEncryption && canSign {
+		doc.Authentication = []VerificationRelationship{NewReferenceRelationship(vmID)}
+		doc.AssertionMethod = []VerificationRelationship{NewReferenceRelationship(vmID)}
+		doc.CapabilityInvocation = []VerificationRelationship{NewReferenceRelationship(vmID)}
+		doc.CapabilityDelegation = []VerificationRelationship{NewReferenceRelationship(vmID)}
+	}
+	if use != JwkUseSignature && canAgree {
+		doc.KeyAgreement = []KeyAgreement{NewReferenceKeyAgreement(vmID)}
+	}
+	return doc, nil
+}
+
+// JWKResolver resolves did:jwk DIDs. Resolution is purely local, no network access is required.
+type JWKResolver struct{}
+
+// NewJWKResolver creates a did:jwk resolver
+func NewJWKResolver() *JWKResolver {
+	return &JWKResolver{}
+}
+
+func (r *JWKResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	doc, err := NewDIDJWKDocument(did)
+	if err != nil {
+		return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+}
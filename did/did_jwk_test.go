@@ -0,0 +1,102 @@
+package did
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDIDJWK(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	key, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.Set(jwk.KeyUsageKey, JwkUseSignature)
+
+	did, err := DIDJWKFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _, _, err := NewJWKResolver().Resolve(context.Background(), did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, did.String()+"#0", doc.VerificationMethod[0].ID)
+	assert.Equal(t, []VerificationRelationship{NewReferenceRelationship(did.String() + "#0")}, doc.Authentication)
+	assert.Empty(t, doc.KeyAgreement)
+
+	publicKey, err := doc.GetVerificationPublicKey("#0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []byte(pub), (*publicKey).([]byte))
+}
+
+func TestDIDJWKEncryption(t *testing.T) {
+	// X25519 example from the did:jwk specification
+	did, err := ParseDID("did:jwk:eyJrdHkiOiJPS1AiLCJjcnYiOiJYMjU1MTkiLCJ1c2UiOiJlbmMiLCJ4IjoiM3A3YmZYdDl3YlRUVzJIQzdPUTFOei1EUThoYmVHZE5yZngtRkctSUswOCJ9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewDIDJWKDocument(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the spec references the verification method from keyAgreement
+	assert.Equal(t, did.String()+"#0", doc.VerificationMethod[0].ID)
+	assert.Empty(t, doc.Authentication)
+	assert.Equal(t, []KeyAgreement{NewReferenceKeyAgreement(did.String() + "#0")}, doc.KeyAgreement)
+	ka, err := doc.FindKeyAgreement("#0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ka.GetPublicKey()
+	assert.NoError(t, err)
+	assert.NoError(t, doc.Validate())
+
+	b, _ := json.Marshal(doc)
+	assert.Contains(t, string(b), `"keyAgreement":["`+did.String()+`#0"]`)
+	var parsed Document
+	assert.NoError(t, json.Unmarshal(b, &parsed))
+	assert.Equal(t, doc.KeyAgreement, parsed.KeyAgreement)
+}
+
+func TestDIDJWKWithoutUse(t *testing.T) {
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for _, raw := range []interface{}{edPriv, p256} {
+		key, _ := jwk.FromRaw(raw)
+		did, err := DIDJWKFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, _, _, err := NewJWKResolver().Resolve(context.Background(), did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NoError(t, doc.Validate())
+		assert.Len(t, doc.VerificationMethod, 1)
+		assert.Len(t, doc.Authentication, 1)
+		if _, ok := raw.(ed25519.PrivateKey); ok {
+			// ed25519 keys can't be used for key agreement
+			assert.Empty(t, doc.KeyAgreement)
+		} else {
+			assert.Equal(t, []KeyAgreement{NewReferenceKeyAgreement(did.String() + "#0")}, doc.KeyAgreement)
+		}
+	}
+}
+
+func TestDIDJWKRejectsPrivateKey(t *testing.T) {
+	// {"kty":"oct","k":"c2VjcmV0"}
+	did, _ := ParseDID("did:jwk:eyJrdHkiOiJvY3QiLCJrIjoiYzJWamNtVjAifQ")
+	_, err := NewDIDJWKDocument(did)
+	assert.ErrorIs(t, err, ErrInvalidDID)
+}
@@ -0,0 +1,264 @@
+package did
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+const (
+	DIDMethodKey = "key"
+)
+
+// DIDKeyFromPublicKey encodes the public key as a did:key (https://w3c-ccg.github.io/did-method-key/).
+// Supported keys are ed25519.PublicKey, *ecdh.PublicKey (X25519, P-256 and P-384), *ecdsa.PublicKey (P-256 and P-384),
+// *secp256k1.PublicKey and *rsa.PublicKey.
+func DIDKeyFromPublicKey(publicKey crypto.PublicKey) (DID, error) {
+	code, raw, err := multicodecPublicKey(publicKey)
+	if err != nil {
+		return DID{}, err
+	}
+	return ParseDID("did:key:" + encodeMultibaseBase58(MulticodecEncode(code, raw)))
+}
+
+// PublicKeyFromDIDKey decodes the public key encoded in the did:key method-specific id.
+// Returned keys are of the same types DIDKeyFromPublicKey accepts (NIST curve keys are returned as *ecdsa.PublicKey).
+func PublicKeyFromDIDKey(did DID) (crypto.PublicKey, error) {
+	if did.Protocol() != DIDMethodKey {
+		return nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:key: %s", did.String())
+	}
+	return decodeMultibasePublicKey(did.Value())
+}
+
+// NewDIDKeyDocument generates the deterministic DID document of a did:key.
+// Ed25519 keys additionally get the derived X25519 key agreement method.
+func NewDIDKeyDocument(did DID) (*Document, error) {
+	if did.Protocol() != DIDMethodKey {
+		return nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:key: %s", did.String())
+	}
+	return newSingleKeyDocument("did:"+did.Protocol()+":"+did.Value(), did.Value())
+}
+
+// newSingleKeyDocument creates a document of a DID derived from a single multibase encoded key (did:key, did:peer:0)
+func newSingleKeyDocument(id string, multibaseKey string) (*Document, error) {
+	publicKey, err := decodeMultibasePublicKey(multibaseKey)
+	if err != nil {
+		return nil, err
+	}
+	base, err := ParseDID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Context: []string{
+			CtxDIDv1,
+			CtxSecJWS2020v1,
+		},
+		ID: base,
+	}
+
+	if xk, ok := publicKey.(*ecdh.PublicKey); ok && xk.Curve() == ecdh.X25519() {
+		ka, kaErr := newJwkKeyAgreement(id, multibaseKey, xk.Bytes())
+		if kaErr != nil {
+			return nil, kaErr
+		}
+		doc.KeyAgreement = []KeyAgreement{ka}
+		return doc, nil
+	}
+
+	pk, err := publicKeyToJwk(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	vmID := id + "#" + multibaseKey
+	doc.VerificationMethod = []VerificationMethod{
+		{
+			ID:           vmID,
+			Type:         PublicKeyJwkType,
+			Controller:   id,
+			PublicKeyJwk: pk,
+		},
+	}
+	doc.Authentication = []VerificationRelationship{NewReferenceRelationship(vmID)}
+	doc.AssertionMethod = []VerificationRelationship{NewReferenceRelationship(vmID)}
+	doc.CapabilityInvocation = []VerificationRelationship{NewReferenceRelationship(vmID)}
+	doc.CapabilityDelegation = []VerificationRelationship{NewReferenceRelationship(vmID)}
+
+	if ek, ok := publicKey.(ed25519.PublicKey); ok {
+		xk, xErr := Ed25519PublicKeyToX25519(ek)
+		if xErr != nil {
+			return nil, xErr
+		}
+		fragment := encodeMultibaseBase58(MulticodecEncode(MCx25519, xk.Bytes()))
+		ka, kaErr := newJwkKeyAgreement(id, fragment, xk.Bytes())
+		if kaErr != nil {
+			return nil, kaErr
+		}
+		doc.KeyAgreement = []KeyAgreement{ka}
+	}
+	return doc, nil
+}
+
+// KeyResolver resolves did:key DIDs. Resolution is purely local, no network access is required.
+type KeyResolver struct{}
+
+// NewKeyResolver creates a did:key resolver
+func NewKeyResolver() *KeyResolver {
+	return &KeyResolver{}
+}
+
+func (r *KeyResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	doc, err := NewDIDKeyDocument(did)
+	if err != nil {
+		code := ErrorCode(err)
+		if code == "" {
+			code = ErrCodeInvalidDID
+			err = newResolutionError(code, "%v", err)
+		}
+		return nil, &ResolutionMetadata{Error: code}, nil, err
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+}
+
+func newJwkKeyAgreement(controller string, fragment string, x25519Key []byte) (KeyAgreement, error) {
+	xk, err := ecdh.X25519().NewPublicKey(x25519Key)
+	if err != nil {
+		return KeyAgreement{}, err
+	}
+	pk, err := x25519PublicKeyToJwk(xk)
+	if err != nil {
+		return KeyAgreement{}, err
+	}
+	return KeyAgreement{
+		ID:           controller + "#" + fragment,
+		Type:         PublicKeyJwkType,
+		Controller:   controller,
+		PublicKeyJwk: pk,
+	}, nil
+}
+
+// multicodecPublicKey returns the multicodec code and the raw (compressed for EC keys) bytes of the public key
+func multicodecPublicKey(publicKey crypto.PublicKey) (uint64, []byte, error) {
+	switch k := publicKey.(type) {
+	case ed25519.PublicKey:
+		return MCed25519, []byte(k), nil
+	case *ecdh.PublicKey:
+		switch k.Curve() {
+		case ecdh.X25519():
+			return MCx25519, k.Bytes(), nil
+		case ecdh.P256():
+			// uncompressed point 0x04 || x || y
+			x, y := elliptic.Unmarshal(elliptic.P256(), k.Bytes())
+			return MCp256, elliptic.MarshalCompressed(elliptic.P256(), x, y), nil
+		case ecdh.P384():
+			x, y := elliptic.Unmarshal(elliptic.P384(), k.Bytes())
+			return MCp384, elliptic.MarshalCompressed(elliptic.P384(), x, y), nil
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return MCp256, elliptic.MarshalCompressed(k.Curve, k.X, k.Y), nil
+		case elliptic.P384():
+			return MCp384, elliptic.MarshalCompressed(k.Curve, k.X, k.Y), nil
+		}
+	case *secp256k1.PublicKey:
+		return MCsecp256k1, k.SerializeCompressed(), nil
+	case *rsa.PublicKey:
+		return MCrsa, x509.MarshalPKCS1PublicKey(k), nil
+	}
+	return 0, nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, publicKey)
+}
+
+// decodeMultibasePublicKey decodes a base58btc multibase, multicodec prefixed public key (e.g. z6Mk...) of a DID
+func decodeMultibasePublicKey(value string) (crypto.PublicKey, error) {
+	if !strings.HasPrefix(value, string(MultibaseBase58BTC)) {
+		return nil, newResolutionError(ErrCodeInvalidDID, "public key must be base58btc multibase encoded: %s", value)
+	}
+	publicKey, err := DecodePublicKeyMultibase(value)
+	if err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "%v", err)
+	}
+	return publicKey, nil
+}
+
+// parseMulticodecPublicKey parses the raw public key bytes of the multicodec key type
+func parseMulticodecPublicKey(code uint64, raw []byte) (crypto.PublicKey, error) {
+	switch code {
+	case MCed25519:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid ed25519 public key size: %d", len(raw))
+		}
+		return ed25519.PublicKey(raw), nil
+	case MCx25519:
+		k, err := ecdh.X25519().NewPublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x25519 public key: %w", err)
+		}
+		return k, nil
+	case MCp256:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), raw)
+		if x == nil {
+			return nil, fmt.Errorf("invalid p-256 public key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case MCp384:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P384(), raw)
+		if x == nil {
+			return nil, fmt.Errorf("invalid p-384 public key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P384(), X: x, Y: y}, nil
+	case MCrsa:
+		k, err := x509.ParsePKCS1PublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rsa public key: %w", err)
+		}
+		return k, nil
+	case MCsecp256k1:
+		k, err := secp256k1.ParsePubKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+		}
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: multicodec 0x%x", ErrUnsupportedKeyType, code)
+}
+
+// publicKeyToJwk wraps the public key in a PublicKeyJwk. secp256k1 is encoded by hand because
+// jwx only supports it when compiled with the jwx_es256k build tag.
+func publicKeyToJwk(publicKey crypto.PublicKey) (*PublicKeyJwk, error) {
+	if k, ok := publicKey.(*secp256k1.PublicKey); ok {
+		uncompressed := k.SerializeUncompressed()
+		raw, err := json.Marshal(map[string]string{
+			"kty": "EC",
+			"crv": "secp256k1",
+			"x":   base64.RawURLEncoding.EncodeToString(uncompressed[1:33]),
+			"y":   base64.RawURLEncoding.EncodeToString(uncompressed[33:65]),
+		})
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwk.ParseKey(raw)
+		if err != nil {
+			return nil, err
+		}
+		return &PublicKeyJwk{Key: key}, nil
+	}
+	key, err := jwk.FromRaw(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedKeyType, err)
+	}
+	return &PublicKeyJwk{Key: key}, nil
+}
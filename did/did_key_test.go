@@ -0,0 +1,97 @@
+package did
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestDIDKeyEd25519(t *testing.T) {
+	// test vector from https://w3c-ccg.github.io/did-method-key/
+	did, err := ParseDID("did:key:z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _, _, err := NewKeyResolver().Resolve(context.Background(), did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, did.String()+"#z6MkiTBz1ymuepAQ4HEHYSF1H8quG5GLVVQR3djdX3mDooWp", doc.VerificationMethod[0].ID)
+	assert.Equal(t, did.String()+"#z6LShs9GGnqk85isEBzzshkuVWrVKsRp24GnDuHk8QWkARMW", doc.KeyAgreement[0].ID)
+
+	publicKey, err := PublicKeyFromDIDKey(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roundTrip, err := DIDKeyFromPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, did.String(), roundTrip.String())
+}
+
+func TestDIDKeyDerivedKeyAgreement(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	did, err := DIDKeyFromPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewDIDKeyDocument(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kaPublicKey, err := doc.KeyAgreement[0].GetPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// X25519 private key of an Ed25519 key is the clamped first half of SHA-512(seed)
+	h := sha512.Sum512(priv.Seed())
+	expected, _ := curve25519.X25519(h[:32], curve25519.Basepoint)
+	assert.Equal(t, expected, (*kaPublicKey).(*ecdh.PublicKey).Bytes())
+}
+
+func TestDIDKeyKeyTypes(t *testing.T) {
+	xk, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	pk, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	sk, _ := secp256k1.GeneratePrivateKey()
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rk, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	for _, publicKey := range []interface{}{xk.PublicKey(), &pk.PublicKey, sk.PubKey(), &p384.PublicKey, &rk.PublicKey} {
+		did, err := DIDKeyFromPublicKey(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := PublicKeyFromDIDKey(did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip, err := DIDKeyFromPublicKey(decoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, did.String(), roundTrip.String())
+		_, err = NewDIDKeyDocument(did)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	did, _ := DIDKeyFromPublicKey(xk.PublicKey())
+	assert.Contains(t, did.String(), "did:key:z6LS")
+	did, _ = DIDKeyFromPublicKey(&pk.PublicKey)
+	assert.Contains(t, did.String(), "did:key:zDn")
+	did, _ = DIDKeyFromPublicKey(sk.PubKey())
+	assert.Contains(t, did.String(), "did:key:zQ3s")
+}
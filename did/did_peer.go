@@ -0,0 +1,476 @@
+package did
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DIDMethodPeer = "peer"
+)
+
+// PeerPurpose is the purpose code prefixing each element of a did:peer:2
+type PeerPurpose byte
+
+// did:peer:2 purpose codes (https://identity.foundation/peer-did-method-spec/#method-2-multiple-inception-key-without-doc)
+const (
+	PeerPurposeAssertion            PeerPurpose = 'A'
+	PeerPurposeEncryption           PeerPurpose = 'E'
+	PeerPurposeVerification         PeerPurpose = 'V'
+	PeerPurposeCapabilityInvocation PeerPurpose = 'I'
+	PeerPurposeCapabilityDelegation PeerPurpose = 'D'
+	PeerPurposeService              PeerPurpose = 'S'
+)
+
+// PeerKey is a key of a did:peer:2 together with its purpose
+type PeerKey struct {
+	Purpose   PeerPurpose
+	PublicKey crypto.PublicKey
+}
+
+// abbreviated service as encoded in did:peer:2
+type peerService struct {
+	ID              string           `json:"id,omitempty"`
+	Type            string           `json:"t"`
+	ServiceEndpoint *json.RawMessage `json:"s"`
+}
+
+type peerServiceEndpoint struct {
+	URI         string   `json:"uri"`
+	Accept      []string `json:"a,omitempty"`
+	RoutingKeys []string `json:"r,omitempty"`
+}
+
+// legacy flat abbreviated service where accept and routing keys are siblings of the endpoint
+type peerLegacyService struct {
+	Accept      []string `json:"a,omitempty"`
+	RoutingKeys []string `json:"r,omitempty"`
+}
+
+var peerServiceTypeAbbreviations = map[string]string{
+	MessagingDIDType: "dm",
+}
+
+// NewPeerDID0 creates a did:peer:0 from a single inception key
+func NewPeerDID0(publicKey crypto.PublicKey) (DID, error) {
+	code, raw, err := multicodecPublicKey(publicKey)
+	if err != nil {
+		return DID{}, err
+	}
+	return ParseDID("did:peer:0" + encodeMultibaseBase58(MulticodecEncode(code, raw)))
+}
+
+// NewPeerDID2 creates a did:peer:2 from the keys and services. Services are encoded in the abbreviated form,
+// Accept and RoutingKeys of a service are kept in the service endpoint object.
+func NewPeerDID2(keys []PeerKey, services []Service) (DID, error) {
+	var sb strings.Builder
+	sb.WriteString("did:peer:2")
+	for _, k := range keys {
+		if k.Purpose == PeerPurposeService {
+			return DID{}, fmt.Errorf("services must be passed as services")
+		}
+		code, raw, err := multicodecPublicKey(k.PublicKey)
+		if err != nil {
+			return DID{}, err
+		}
+		sb.WriteString(".")
+		sb.WriteByte(byte(k.Purpose))
+		sb.WriteString(encodeMultibaseBase58(MulticodecEncode(code, raw)))
+	}
+	for i, s := range services {
+		endpoint, err := json.Marshal(&peerServiceEndpoint{
+			URI:         s.ServiceEndpoint,
+			Accept:      s.Accept,
+			RoutingKeys: s.RoutingKeys,
+		})
+		if err != nil {
+			return DID{}, err
+		}
+		ps := peerService{
+			Type:            s.Type,
+			ServiceEndpoint: (*json.RawMessage)(&endpoint),
+		}
+		if abbr, ok := peerServiceTypeAbbreviations[s.Type]; ok {
+			ps.Type = abbr
+		}
+		if s.ID != "" && s.ID != peerServiceID(i) {
+			ps.ID = s.ID
+		}
+		encoded, err := json.Marshal(&ps)
+		if err != nil {
+			return DID{}, err
+		}
+		sb.WriteString(".")
+		sb.WriteByte(byte(PeerPurposeService))
+		sb.WriteString(base64.RawURLEncoding.EncodeToString(encoded))
+	}
+	return ParseDID(sb.String())
+}
+
+// NewPeerDID4 creates the long and short form of a did:peer:4 from an input document.
+// The input document ID is ignored, ids of verification methods should be relative (e.g. "#key-1").
+func NewPeerDID4(doc *Document) (longForm DID, shortForm DID, err error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return DID{}, DID{}, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return DID{}, DID{}, err
+	}
+	delete(m, "id")
+	b, err = json.Marshal(m)
+	if err != nil {
+		return DID{}, DID{}, err
+	}
+
+	encoded := encodeMultibaseBase58(MulticodecEncode(MCjson, b))
+	hash := peerDID4Hash(encoded)
+	longForm, err = ParseDID("did:peer:4" + hash + ":" + encoded)
+	if err != nil {
+		return DID{}, DID{}, err
+	}
+	shortForm, err = ParseDID("did:peer:4" + hash)
+	if err != nil {
+		return DID{}, DID{}, err
+	}
+	return longForm, shortForm, nil
+}
+
+// NewPeerDIDDocument generates the document of a did:peer:0, did:peer:2 or a long form did:peer:4
+func NewPeerDIDDocument(did DID) (*Document, error) {
+	if did.Protocol() != DIDMethodPeer {
+		return nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:peer: %s", did.String())
+	}
+	value := did.Value()
+	if value == "" {
+		return nil, newResolutionError(ErrCodeInvalidDID, "empty did:peer")
+	}
+	id := "did:" + did.Protocol() + ":" + value
+	switch value[0] {
+	case '0':
+		return newSingleKeyDocument(id, value[1:])
+	case '2':
+		return newPeerDID2Document(id, value[1:])
+	case '4':
+		return newPeerDID4Document(id, value[1:])
+	}
+	return nil, newResolutionError(ErrCodeInvalidDID, "unsupported did:peer numalgo %q", value[0])
+}
+
+func newPeerDID2Document(id string, elements string) (*Document, error) {
+	base, err := ParseDID(id)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{
+		Context: []string{
+			CtxDIDv1,
+			CtxSecJWS2020v1,
+		},
+		ID: base,
+	}
+	if !strings.HasPrefix(elements, ".") {
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:peer:2 elements must start with '.'")
+	}
+
+	keyIndex, serviceIndex := 0, 0
+	for _, element := range strings.Split(elements[1:], ".") {
+		if len(element) < 2 {
+			return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:2 element %q", element)
+		}
+		purpose, value := PeerPurpose(element[0]), element[1:]
+		if purpose == PeerPurposeService {
+			s, sErr := decodePeerService(value, serviceIndex)
+			if sErr != nil {
+				return nil, sErr
+			}
+			doc.Service = append(doc.Service, *s)
+			serviceIndex++
+			continue
+		}
+
+		keyIndex++
+		fragment := "key-" + strconv.Itoa(keyIndex)
+		publicKey, pErr := decodeMultibasePublicKey(value)
+		if pErr != nil {
+			return nil, pErr
+		}
+		switch purpose {
+		case PeerPurposeEncryption:
+			code, raw, cErr := multicodecPublicKey(publicKey)
+			if cErr != nil || code != MCx25519 {
+				return nil, newResolutionError(ErrCodeInvalidDID, "did:peer:2 encryption keys must be x25519")
+			}
+			ka, kaErr := newJwkKeyAgreement(id, fragment, raw)
+			if kaErr != nil {
+				return nil, kaErr
+			}
+			doc.KeyAgreement = append(doc.KeyAgreement, ka)
+		case PeerPurposeVerification, PeerPurposeAssertion, PeerPurposeCapabilityInvocation, PeerPurposeCapabilityDelegation:
+			pk, pkErr := publicKeyToJwk(publicKey)
+			if pkErr != nil {
+				return nil, pkErr
+			}
+			vm := VerificationMethod{
+				ID:           id + "#" + fragment,
+				Type:         PublicKeyJwkType,
+				Controller:   id,
+				PublicKeyJwk: pk,
+			}
+			doc.VerificationMethod = append(doc.VerificationMethod, vm)
+			ref := NewReferenceRelationship(vm.ID)
+			switch purpose {
+			case PeerPurposeVerification:
+				doc.Authentication = append(doc.Authentication, ref)
+			case PeerPurposeAssertion:
+				doc.AssertionMethod = append(doc.AssertionMethod, ref)
+			case PeerPurposeCapabilityInvocation:
+				doc.CapabilityInvocation = append(doc.CapabilityInvocation, ref)
+			case PeerPurposeCapabilityDelegation:
+				doc.CapabilityDelegation = append(doc.CapabilityDelegation, ref)
+			}
+		default:
+			return nil, newResolutionError(ErrCodeInvalidDID, "unknown did:peer:2 purpose %q", byte(purpose))
+		}
+	}
+	return doc, nil
+}
+
+func decodePeerService(value string, index int) (*Service, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:2 service encoding: %v", err)
+	}
+	var ps peerService
+	if err := json.Unmarshal(b, &ps); err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:2 service: %v", err)
+	}
+	s := &Service{
+		ID:   ps.ID,
+		Type: ps.Type,
+	}
+	if s.ID == "" {
+		s.ID = peerServiceID(index)
+	}
+	for full, abbr := range peerServiceTypeAbbreviations {
+		if s.Type == abbr {
+			s.Type = full
+		}
+	}
+	if ps.ServiceEndpoint == nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:peer:2 service without endpoint")
+	}
+	var uri string
+	if err := json.Unmarshal(*ps.ServiceEndpoint, &uri); err == nil {
+		var legacy peerLegacyService
+		if lErr := json.Unmarshal(b, &legacy); lErr != nil {
+			return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:2 service: %v", lErr)
+		}
+		s.ServiceEndpoint = uri
+		s.Accept = legacy.Accept
+		s.RoutingKeys = legacy.RoutingKeys
+		return s, nil
+	}
+	var endpoint peerServiceEndpoint
+	if err := json.Unmarshal(*ps.ServiceEndpoint, &endpoint); err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:2 service endpoint: %v", err)
+	}
+	s.ServiceEndpoint = endpoint.URI
+	s.Accept = endpoint.Accept
+	s.RoutingKeys = endpoint.RoutingKeys
+	return s, nil
+}
+
+// service ids are "#service", "#service-1", "#service-2", ... in order of appearance
+func peerServiceID(index int) string {
+	if index == 0 {
+		return "#service"
+	}
+	return "#service-" + strconv.Itoa(index)
+}
+
+func newPeerDID4Document(id string, value string) (*Document, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return nil, newResolutionError(ErrCodeNotFound, "short form did:peer:4 can only be resolved after its long form: %s", id)
+	}
+	hash, encoded := parts[0], parts[1]
+	if peerDID4Hash(encoded) != hash {
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:peer:4 hash does not match the encoded document")
+	}
+	decoded, err := decodeMultibase(encoded)
+	if err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "%v", err)
+	}
+	code, raw, err := MulticodecDecode(decoded)
+	if err != nil || code != MCjson {
+		return nil, newResolutionError(ErrCodeInvalidDID, "did:peer:4 document must be multicodec json")
+	}
+
+	var doc Document
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, newResolutionError(ErrCodeInvalidDID, "invalid did:peer:4 document: %v", err)
+	}
+	longForm, err := ParseDID(id)
+	if err != nil {
+		return nil, err
+	}
+	doc.ID = longForm
+	if len(doc.Context) == 0 {
+		doc.Context = []string{CtxDIDv1}
+	}
+	doc.AlsoKnownAs = append(doc.AlsoKnownAs, "did:peer:4"+hash)
+	for i := range doc.VerificationMethod {
+		if doc.VerificationMethod[i].Controller == "" {
+			doc.VerificationMethod[i].Controller = id
+		}
+	}
+	for i := range doc.KeyAgreement {
+		if doc.KeyAgreement[i].Controller == "" {
+			doc.KeyAgreement[i].Controller = id
+		}
+	}
+	return &doc, nil
+}
+
+// peerDID4Hash is the base58btc multibase of the sha2-256 multihash of the encoded document
+func peerDID4Hash(encoded string) string {
+	return multihashSHA256([]byte(encoded))
+}
+
+// maximum number of short form did:peer:4 documents remembered by a PeerResolver
+const maxPeerShortForms = 1024
+
+// PeerResolver resolves did:peer DIDs. Short form did:peer:4 DIDs are resolvable once their long form was resolved,
+// the documents of the last maxPeerShortForms long forms are remembered.
+type PeerResolver struct {
+	mu         sync.RWMutex
+	shortForms map[string]*Document
+	order      []string // short forms, oldest first
+}
+
+// NewPeerResolver creates a did:peer resolver
+func NewPeerResolver() *PeerResolver {
+	return &PeerResolver{
+		shortForms: make(map[string]*Document),
+	}
+}
+
+func (r *PeerResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	id := "did:" + did.Protocol() + ":" + did.Value()
+	if strings.HasPrefix(did.Value(), "4") && !strings.Contains(did.Value(), ":") {
+		r.mu.RLock()
+		doc, ok := r.shortForms[id]
+		r.mu.RUnlock()
+		if !ok {
+			return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, newResolutionError(ErrCodeNotFound, "%s", id)
+		}
+		return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+	}
+
+	doc, err := NewPeerDIDDocument(did)
+	if err != nil {
+		code := ErrorCode(err)
+		if code == "" {
+			code = ErrCodeInvalidDID
+			err = newResolutionError(code, "%v", err)
+		}
+		return nil, &ResolutionMetadata{Error: code}, nil, err
+	}
+	docMeta := &DocumentMetadata{}
+	if strings.HasPrefix(did.Value(), "4") {
+		short := doc.AlsoKnownAs[len(doc.AlsoKnownAs)-1]
+		shortDoc, sErr := shortFormPeerDocument(doc, short)
+		if sErr != nil {
+			return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, newResolutionError(ErrCodeNotFound, "%s: %v", short, sErr)
+		}
+		r.remember(short, shortDoc)
+		docMeta.CanonicalID = short
+		docMeta.EquivalentID = []string{short}
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, docMeta, nil
+}
+
+// remember stores the short form document, evicting the oldest one when maxPeerShortForms is reached
+func (r *PeerResolver) remember(short string, doc *Document) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.shortForms[short]; !ok {
+		if len(r.order) >= maxPeerShortForms {
+			delete(r.shortForms, r.order[0])
+			r.order = r.order[1:]
+		}
+		r.order = append(r.order, short)
+	}
+	r.shortForms[short] = doc
+}
+
+// shortFormPeerDocument contextualizes the long form document for its short form DID: the document id,
+// controllers, method, relationship and service ids refer to the short form and the long form is added to alsoKnownAs
+func shortFormPeerDocument(longDoc *Document, short string) (*Document, error) {
+	long := longDoc.ID.String()
+	shortDID, err := ParseDID(short)
+	if err != nil {
+		return nil, err
+	}
+	// copy the long form document, it's returned by the resolver as well
+	b, err := json.Marshal(longDoc)
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	contextualize := func(id string) string {
+		if rest, ok := strings.CutPrefix(id, long); ok && (rest == "" || strings.ContainsAny(rest[:1], "#?/")) {
+			return short + rest
+		}
+		return id
+	}
+	doc.ID = shortDID
+	for i := range doc.Controller {
+		doc.Controller[i] = contextualize(doc.Controller[i])
+	}
+	for i := range doc.VerificationMethod {
+		doc.VerificationMethod[i].ID = contextualize(doc.VerificationMethod[i].ID)
+		doc.VerificationMethod[i].Controller = contextualize(doc.VerificationMethod[i].Controller)
+	}
+	for i := range doc.KeyAgreement {
+		doc.KeyAgreement[i].ID = contextualize(doc.KeyAgreement[i].ID)
+		doc.KeyAgreement[i].Controller = contextualize(doc.KeyAgreement[i].Controller)
+	}
+	for _, relationship := range [][]VerificationRelationship{doc.Authentication, doc.AssertionMethod, doc.CapabilityInvocation, doc.CapabilityDelegation} {
+		for i := range relationship {
+			if relationship[i].Method == nil {
+				relationship[i].Reference = contextualize(relationship[i].Reference)
+				continue
+			}
+			relationship[i].Method.ID = contextualize(relationship[i].Method.ID)
+			relationship[i].Method.Controller = contextualize(relationship[i].Method.Controller)
+		}
+	}
+	for i := range doc.Service {
+		doc.Service[i].ID = contextualize(doc.Service[i].ID)
+		for j := range doc.Service[i].RoutingKeys {
+			doc.Service[i].RoutingKeys[j] = contextualize(doc.Service[i].RoutingKeys[j])
+		}
+	}
+
+	alsoKnownAs := make([]string, 0, len(doc.AlsoKnownAs))
+	for _, aka := range doc.AlsoKnownAs {
+		if aka != short {
+			alsoKnownAs = append(alsoKnownAs, aka)
+		}
+	}
+	doc.AlsoKnownAs = append(alsoKnownAs, long)
+	return &doc, nil
+}
@@ -0,0 +1,169 @@
+package did
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerDID0(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	did, err := NewPeerDID0(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _, _, err := NewPeerResolver().Resolve(context.Background(), did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, did.String(), doc.ID.String())
+	assert.Len(t, doc.VerificationMethod, 1)
+	assert.Len(t, doc.KeyAgreement, 1)
+}
+
+func TestPeerDID2(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	xk, _ := ecdh.X25519().GenerateKey(rand.Reader)
+	did, err := NewPeerDID2([]PeerKey{
+		{Purpose: PeerPurposeEncryption, PublicKey: xk.PublicKey()},
+		{Purpose: PeerPurposeVerification, PublicKey: pub},
+	}, []Service{
+		{
+			Type:            MessagingDIDType,
+			ServiceEndpoint: "https://msg.mailio.com/didcomm",
+			Accept:          []string{"didcomm/v2"},
+			RoutingKeys:     []string{"did:example:123456789abcdefghi#key-1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewPeerDIDDocument(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, did.String()+"#key-1", doc.KeyAgreement[0].ID)
+	assert.Equal(t, did.String()+"#key-2", doc.VerificationMethod[0].ID)
+	assert.Equal(t, []VerificationRelationship{NewReferenceRelationship(did.String() + "#key-2")}, doc.Authentication)
+	assert.Equal(t, "#service", doc.Service[0].ID)
+	assert.Equal(t, MessagingDIDType, doc.Service[0].Type)
+	assert.Equal(t, "https://msg.mailio.com/didcomm", doc.Service[0].ServiceEndpoint)
+	assert.Equal(t, []string{"didcomm/v2"}, doc.Service[0].Accept)
+	assert.Equal(t, []string{"did:example:123456789abcdefghi#key-1"}, doc.Service[0].RoutingKeys)
+}
+
+func TestPeerDID2LegacyService(t *testing.T) {
+	// service encoded as {"t":"dm","s":"https://example.com/endpoint","r":["did:example:somemediator#somekey"],"a":["didcomm/v2"]}
+	did, err := ParseDID("did:peer:2.Ez6LSbysY2xFMRpGMhb7tFTLMpeuPRaqaWM1yECx2AtzE3KCc.Vz6MkqRYqQiSgvZQdnBytw86Qbs2ZWUkGv22od935YF4s8M7V" +
+		".SeyJ0IjoiZG0iLCJzIjoiaHR0cHM6Ly9leGFtcGxlLmNvbS9lbmRwb2ludCIsInIiOlsiZGlkOmV4YW1wbGU6c29tZW1lZGlhdG9yI3NvbWVrZXkiXSwiYSI6WyJkaWRjb21tL3YyIl19")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewPeerDIDDocument(did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://example.com/endpoint", doc.Service[0].ServiceEndpoint)
+	assert.Equal(t, []string{"did:example:somemediator#somekey"}, doc.Service[0].RoutingKeys)
+	assert.Equal(t, []string{"didcomm/v2"}, doc.Service[0].Accept)
+}
+
+func TestPeerDID4(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	pk, err := publicKeyToJwk(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := &Document{
+		Context: []string{CtxDIDv1, CtxSecJWS2020v1},
+		VerificationMethod: []VerificationMethod{
+			{ID: "#key-1", Type: PublicKeyJwkType, PublicKeyJwk: pk},
+		},
+		Authentication: []VerificationRelationship{NewReferenceRelationship("#key-1")},
+		CapabilityInvocation: []VerificationRelationship{
+			NewEmbeddedRelationship(VerificationMethod{ID: "#key-2", Type: PublicKeyJwkType, PublicKeyJwk: pk}),
+		},
+		Service:     []Service{{ID: "#didcomm", Type: MessagingDIDType, ServiceEndpoint: MessageServiceEndpoint}},
+		AlsoKnownAs: []string{"https://mail.io/alice"},
+	}
+	longForm, shortForm, err := NewPeerDID4(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewPeerResolver()
+	_, _, _, err = resolver.Resolve(context.Background(), shortForm)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	doc, _, meta, err := resolver.Resolve(context.Background(), longForm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, longForm.String(), doc.ID.String())
+	assert.Equal(t, []string{"https://mail.io/alice", shortForm.String()}, doc.AlsoKnownAs)
+	assert.Equal(t, shortForm.String(), meta.CanonicalID)
+	_, err = doc.GetVerificationPublicKey("#key-1")
+	assert.NoError(t, err)
+
+	doc, _, _, err = resolver.Resolve(context.Background(), shortForm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, shortForm.String(), doc.ID.String())
+	assert.Equal(t, []string{"https://mail.io/alice", longForm.String()}, doc.AlsoKnownAs)
+	assert.Equal(t, shortForm.String(), doc.VerificationMethod[0].Controller)
+	assert.Equal(t, "#key-1", doc.Authentication[0].ID())
+	assert.Equal(t, "#key-2", doc.CapabilityInvocation[0].ID())
+	assert.Equal(t, "#didcomm", doc.Service[0].ID)
+	assert.NoError(t, doc.Validate())
+	// the long form document isn't modified
+	long, _, _, _ := resolver.Resolve(context.Background(), longForm)
+	assert.Equal(t, longForm.String(), long.VerificationMethod[0].Controller)
+
+	// absolute ids of the long form are rewritten, others are kept
+	l := longForm.String()
+	absolute := &Document{
+		ID:                 longForm,
+		Controller:         ControllerSet{l, "did:example:alice"},
+		VerificationMethod: []VerificationMethod{{ID: l + "#key-1", Type: PublicKeyJwkType, Controller: l, PublicKeyJwk: pk}},
+		Authentication:     []VerificationRelationship{NewReferenceRelationship(l + "#key-1")},
+		CapabilityInvocation: []VerificationRelationship{
+			NewEmbeddedRelationship(VerificationMethod{ID: l + "#key-2", Type: PublicKeyJwkType, Controller: l, PublicKeyJwk: pk}),
+		},
+		Service:     []Service{{ID: l + "#didcomm", Type: MessagingDIDType, ServiceEndpoint: MessageServiceEndpoint}},
+		AlsoKnownAs: []string{shortForm.String()},
+	}
+	short, err := shortFormPeerDocument(absolute, shortForm.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := shortForm.String()
+	assert.Equal(t, ControllerSet{s, "did:example:alice"}, short.Controller)
+	assert.Equal(t, s+"#key-1", short.VerificationMethod[0].ID)
+	assert.Equal(t, s, short.VerificationMethod[0].Controller)
+	assert.Equal(t, s+"#key-1", short.Authentication[0].ID())
+	assert.Equal(t, s+"#key-2", short.CapabilityInvocation[0].ID())
+	assert.Equal(t, s, short.CapabilityInvocation[0].Method.Controller)
+	assert.Equal(t, s+"#didcomm", short.Service[0].ID)
+	assert.Equal(t, []string{l}, short.AlsoKnownAs)
+	assert.Equal(t, l+"#key-1", absolute.VerificationMethod[0].ID)
+
+	// only the most recent short forms are remembered
+	for i := 0; i < maxPeerShortForms; i++ {
+		resolver.remember(fmt.Sprintf("did:peer:4zQm%d", i), doc)
+	}
+	assert.Len(t, resolver.shortForms, maxPeerShortForms)
+	_, _, _, err = resolver.Resolve(context.Background(), shortForm)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// tampered document doesn't match the hash
+	tampered, _ := ParseDID(longForm.String()[:len(longForm.String())-2] + "11")
+	_, err = NewPeerDIDDocument(tampered)
+	assert.ErrorIs(t, err, ErrInvalidDID)
+}
@@ -0,0 +1,167 @@
+package did
+
+import (
+	"context"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/mr-tron/base58"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	DIDMethodPKH = "pkh"
+
+	// CAIP-2 namespaces supported by did:pkh
+	CAIPNamespaceEIP155 = "eip155"
+	CAIPNamespaceSolana = "solana"
+	CAIPNamespaceBIP122 = "bip122"
+)
+
+var (
+	caipNamespaceRegex = regexp.MustCompile(`^[-a-z0-9]{3,8}$`)
+	caipReferenceRegex = regexp.MustCompile(`^[-_a-zA-Z0-9]{1,32}$`)
+	caipAddressRegex   = regexp.MustCompile(`^[-.%a-zA-Z0-9]{1,128}$`)
+	eip155AddressRegex = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+	bip122ChainRegex   = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// AccountID is a CAIP-10 blockchain account id: namespace:reference:address (e.g. eip155:1:0xab16a96d359ec26a11e2c2b3d8f8b8942d5bfcdb)
+type AccountID struct {
+	Namespace string
+	Reference string
+	Address   string
+}
+
+func (a AccountID) String() string {
+	return a.Namespace + ":" + a.Reference + ":" + a.Address
+}
+
+// ChainID returns the CAIP-2 chain id (namespace:reference)
+func (a AccountID) ChainID() string {
+	return a.Namespace + ":" + a.Reference
+}
+
+// ParseAccountID parses and validates a CAIP-10 account id of one of the supported namespaces (eip155, solana, bip122)
+func ParseAccountID(s string) (AccountID, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return AccountID{}, newResolutionError(ErrCodeInvalidDID, "account id must be namespace:reference:address: %q", s)
+	}
+	a := AccountID{
+		Namespace: parts[0],
+		Reference: parts[1],
+		Address:   parts[2],
+	}
+	if !caipNamespaceRegex.MatchString(a.Namespace) || !caipReferenceRegex.MatchString(a.Reference) || !caipAddressRegex.MatchString(a.Address) {
+		return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid CAIP-10 account id: %q", s)
+	}
+
+	switch a.Namespace {
+	case CAIPNamespaceEIP155:
+		if !eip155AddressRegex.MatchString(a.Address) {
+			return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid eip155 address: %q", a.Address)
+		}
+		// mixed case addresses carry an EIP-55 checksum
+		if strings.ToLower(a.Address) != a.Address && strings.ToUpper(a.Address[2:]) != a.Address[2:] && eip55Checksum(a.Address) != a.Address {
+			return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid eip155 address checksum: %q", a.Address)
+		}
+	case CAIPNamespaceSolana:
+		pk, err := base58.Decode(a.Address)
+		if err != nil || len(pk) != 32 {
+			return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid solana address: %q", a.Address)
+		}
+	case CAIPNamespaceBIP122:
+		if !bip122ChainRegex.MatchString(a.Reference) {
+			return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid bip122 chain id: %q", a.Reference)
+		}
+		if _, err := base58.Decode(a.Address); err != nil && !strings.HasPrefix(a.Address, "bc1") {
+			return AccountID{}, newResolutionError(ErrCodeInvalidDID, "invalid bip122 address: %q", a.Address)
+		}
+	default:
+		return AccountID{}, newResolutionError(ErrCodeMethodNotSupported, "unsupported did:pkh namespace: %s", a.Namespace)
+	}
+	return a, nil
+}
+
+// NewPKHDID creates a did:pkh from the CAIP-10 account id
+func NewPKHDID(account AccountID) (DID, error) {
+	if _, err := ParseAccountID(account.String()); err != nil {
+		return DID{}, err
+	}
+	return ParseDID("did:pkh:" + account.String())
+}
+
+// NewDIDPKHDocument generates the document of a did:pkh (https://github.com/w3c-ccg/did-pkh/blob/main/did-pkh-method-draft.md).
+// eip155 and bip122 accounts are verified with EcdsaSecp256k1RecoveryMethod2020, solana accounts with Ed25519VerificationKey2018.
+func NewDIDPKHDocument(did DID) (*Document, error) {
+	if did.Protocol() != DIDMethodPKH {
+		return nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:pkh: %s", did.String())
+	}
+	account, err := ParseAccountID(did.Value())
+	if err != nil {
+		return nil, err
+	}
+	id := "did:" + did.Protocol() + ":" + did.Value()
+	base, err := ParseDID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	vm := VerificationMethod{
+		ID:                  id + "#blockchainAccountId",
+		Controller:          id,
+		BlockchainAccountID: account.String(),
+	}
+	ctx := []string{CtxDIDv1}
+	switch account.Namespace {
+	case CAIPNamespaceSolana:
+		vm.Type = KeyTypeEd25519_2018
+		vm.PublicKeyBase58 = account.Address
+		ctx = append(ctx, CtxSecEd25519_2018v1)
+	default:
+		vm.Type = KeyTypeEcdsaSecp256k1Recovery2020
+		ctx = append(ctx, CtxSecSecp256k1Recovery2020v2)
+	}
+
+	return &Document{
+		Context:            ctx,
+		ID:                 base,
+		VerificationMethod: []VerificationMethod{vm},
+		Authentication:     []VerificationRelationship{NewReferenceRelationship(vm.ID)},
+		AssertionMethod:    []VerificationRelationship{NewReferenceRelationship(vm.ID)},
+	}, nil
+}
+
+// PKHResolver resolves did:pkh DIDs. Resolution is purely local, no network access is required.
+type PKHResolver struct{}
+
+// NewPKHResolver creates a did:pkh resolver
+func NewPKHResolver() *PKHResolver {
+	return &PKHResolver{}
+}
+
+func (r *PKHResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	doc, err := NewDIDPKHDocument(did)
+	if err != nil {
+		return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+}
+
+// eip55Checksum returns the EIP-55 mixed case checksum encoding of an ethereum address
+func eip55Checksum(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte(lower))
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	out := []byte(lower)
+	for i := range out {
+		if out[i] >= 'a' && hash[i] >= '8' {
+			out[i] -= 'a' - 'A'
+		}
+	}
+	return "0x" + string(out)
+}
@@ -0,0 +1,56 @@
+package did
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDIDPKH(t *testing.T) {
+	tests := map[string]string{
+		"did:pkh:eip155:1:0xb9c5714089478a327f09197987f16f9e5d936e8a":                                  KeyTypeEcdsaSecp256k1Recovery2020,
+		"did:pkh:eip155:1:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed":                                  KeyTypeEcdsaSecp256k1Recovery2020,
+		"did:pkh:bip122:000000000019d6689c085ae165831e93:128Lkh3S7CkDTBZ8W7BbpsN3YYizJMp8p6":           KeyTypeEcdsaSecp256k1Recovery2020,
+		"did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:CKg5d12Jhpej1JqtmxLJgaFqqeYjxgPqToJ4LBdvG9Ev": KeyTypeEd25519_2018,
+	}
+	resolver := NewPKHResolver()
+	for d, keyType := range tests {
+		did, err := ParseDID(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, _, _, err := resolver.Resolve(context.Background(), did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, keyType, doc.VerificationMethod[0].Type)
+		assert.Equal(t, d+"#blockchainAccountId", doc.VerificationMethod[0].ID)
+		assert.Equal(t, d[len("did:pkh:"):], doc.VerificationMethod[0].BlockchainAccountID)
+	}
+}
+
+func TestDIDPKHInvalid(t *testing.T) {
+	invalid := []string{
+		"did:pkh:eip155:1:0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", // bad checksum
+		"did:pkh:eip155:1:0x1234",
+		"did:pkh:solana:4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZ:abc",
+		"did:pkh:eip155:0xb9c5714089478a327f09197987f16f9e5d936e8a",
+	}
+	for _, d := range invalid {
+		did, err := ParseDID(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewDIDPKHDocument(did)
+		assert.ErrorIs(t, err, ErrInvalidDID, d)
+	}
+
+	account, _ := ParseAccountID("eip155:1:0xb9c5714089478a327f09197987f16f9e5d936e8a")
+	did, err := NewPKHDID(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "did:pkh:eip155:1:0xb9c5714089478a327f09197987f16f9e5d936e8a", did.String())
+	assert.Equal(t, "eip155:1", account.ChainID())
+}
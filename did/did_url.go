@@ -0,0 +1,269 @@
+package did
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DID parameters with a meaning defined by https://www.w3.org/TR/did-core/#did-parameters
+const (
+	DIDParamService     = "service"
+	DIDParamRelativeRef = "relativeRef"
+	DIDParamVersionID   = "versionId"
+	DIDParamVersionTime = "versionTime"
+	DIDParamHashLink    = "hl"
+)
+
+// DIDURL is a DID followed by an optional path, query and fragment as defined by the DID Core ABNF:
+//
+//	did-url = did path-abempty [ "?" query ] [ "#" fragment ]
+//
+// Path, Query and Fragment are kept in their raw (percent-encoded) form so that String() round-trips the parsed input.
+type DIDURL struct {
+	DID      DID
+	Path     string // path-abempty including the leading "/"
+	Query    string // raw query without the leading "?"
+	Fragment string // raw fragment without the leading "#"
+
+	// whether the "?" and "#" delimiters were present in the parsed input, so empty ones round-trip
+	hasQuery    bool
+	hasFragment bool
+}
+
+// ParseDIDURL parses and strictly validates a DID URL
+func ParseDIDURL(s string) (*DIDURL, error) {
+	rest := s
+	var fragment, query string
+	hasFragment, hasQuery := false, false
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		fragment = rest[i+1:]
+		rest = rest[:i]
+		hasFragment = true
+	}
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		query = rest[i+1:]
+		rest = rest[:i]
+		hasQuery = true
+	}
+	var path string
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		path = rest[i:]
+		rest = rest[:i]
+	}
+
+	if !strings.HasPrefix(rest, "did:") {
+		return nil, newResolutionError(ErrCodeInvalidDID, "must start with 'did:': %s", s)
+	}
+	methodAndID := strings.TrimPrefix(rest, "did:")
+	i := strings.IndexByte(methodAndID, ':')
+	if i < 0 {
+		return nil, newResolutionError(ErrCodeInvalidDID, "missing method-specific-id: %s", s)
+	}
+	method, id := methodAndID[:i], methodAndID[i+1:]
+	if err := validateMethodName(method); err != nil {
+		return nil, err
+	}
+	if err := validateMethodSpecificID(id); err != nil {
+		return nil, err
+	}
+	if err := validatePath(path); err != nil {
+		return nil, err
+	}
+	if hasQuery {
+		if err := validateQueryOrFragment("query", query); err != nil {
+			return nil, err
+		}
+	}
+	if hasFragment {
+		if err := validateQueryOrFragment("fragment", fragment); err != nil {
+			return nil, err
+		}
+	}
+
+	u := &DIDURL{
+		DID: DID{
+			raw:   rest,
+			proto: method,
+			value: id,
+		},
+		Path:        path,
+		Query:       query,
+		Fragment:    fragment,
+		hasQuery:    hasQuery,
+		hasFragment: hasFragment,
+	}
+	if _, ok := u.QueryParams()[DIDParamVersionTime]; ok {
+		if _, err := time.Parse(time.RFC3339, u.QueryParams().Get(DIDParamVersionTime)); err != nil {
+			return nil, newResolutionError(ErrCodeInvalidDID, "invalid versionTime: %v", err)
+		}
+	}
+	return u, nil
+}
+
+// String returns the DID URL in its canonical string form
+func (u *DIDURL) String() string {
+	var sb strings.Builder
+	sb.WriteString(u.DID.raw)
+	sb.WriteString(u.Path)
+	if u.Query != "" || u.hasQuery {
+		sb.WriteString("?")
+		sb.WriteString(u.Query)
+	}
+	if u.Fragment != "" || u.hasFragment {
+		sb.WriteString("#")
+		sb.WriteString(u.Fragment)
+	}
+	return sb.String()
+}
+
+// QueryParams returns the parsed query parameters
+func (u *DIDURL) QueryParams() url.Values {
+	values, err := url.ParseQuery(u.Query)
+	if err != nil {
+		return url.Values{}
+	}
+	return values
+}
+
+// Service returns the value of the "service" DID parameter
+func (u *DIDURL) Service() string {
+	return u.QueryParams().Get(DIDParamService)
+}
+
+// RelativeRef returns the unescaped value of the "relativeRef" DID parameter
+func (u *DIDURL) RelativeRef() string {
+	return u.QueryParams().Get(DIDParamRelativeRef)
+}
+
+// VersionID returns the value of the "versionId" DID parameter
+func (u *DIDURL) VersionID() string {
+	return u.QueryParams().Get(DIDParamVersionID)
+}
+
+// VersionTime returns the value of the "versionTime" DID parameter and whether it was present
+func (u *DIDURL) VersionTime() (time.Time, bool) {
+	v := u.QueryParams().Get(DIDParamVersionTime)
+	if v == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Version returns the document version selected by the "versionId" and "versionTime" DID parameters
+func (u *DIDURL) Version() VersionSelector {
+	versionTime, _ := u.VersionTime()
+	return VersionSelector{
+		VersionID:   u.VersionID(),
+		VersionTime: versionTime,
+	}
+}
+
+// HashLink returns the value of the "hl" DID parameter
+func (u *DIDURL) HashLink() string {
+	return u.QueryParams().Get(DIDParamHashLink)
+}
+
+// method-name = 1*method-char, method-char = %x61-7A / DIGIT
+func validateMethodName(method string) error {
+	if method == "" {
+		return newResolutionError(ErrCodeInvalidDID, "empty method name")
+	}
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		if !(c >= 'a' && c <= 'z') && !isDigit(c) {
+			return newResolutionError(ErrCodeInvalidDID, "invalid character %q in method name %q", c, method)
+		}
+	}
+	return nil
+}
+
+// method-specific-id = *( *idchar ":" ) 1*idchar
+func validateMethodSpecificID(id string) error {
+	if id == "" || strings.HasSuffix(id, ":") {
+		return newResolutionError(ErrCodeInvalidDID, "method-specific-id must not be empty or end with ':': %q", id)
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c == ':':
+		case c == '%':
+			if !isPctEncoded(id, i) {
+				return newResolutionError(ErrCodeInvalidDID, "invalid percent-encoding in method-specific-id %q", id)
+			}
+			i += 2
+		case isIDChar(c):
+		default:
+			return newResolutionError(ErrCodeInvalidDID, "invalid character %q in method-specific-id %q", c, id)
+		}
+	}
+	return nil
+}
+
+// path-abempty = *( "/" segment ), segment = *pchar
+func validatePath(path string) error {
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '/':
+		case c == '%':
+			if !isPctEncoded(path, i) {
+				return newResolutionError(ErrCodeInvalidDID, "invalid percent-encoding in path %q", path)
+			}
+			i += 2
+		case isPChar(c):
+		default:
+			return newResolutionError(ErrCodeInvalidDID, "invalid character %q in path %q", c, path)
+		}
+	}
+	return nil
+}
+
+// query = fragment = *( pchar / "/" / "?" )
+func validateQueryOrFragment(part string, s string) error {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '/' || c == '?':
+		case c == '%':
+			if !isPctEncoded(s, i) {
+				return newResolutionError(ErrCodeInvalidDID, "invalid percent-encoding in %s %q", part, s)
+			}
+			i += 2
+		case isPChar(c):
+		default:
+			return newResolutionError(ErrCodeInvalidDID, "invalid character %q in %s %q", c, part, s)
+		}
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isPctEncoded(s string, i int) bool {
+	return i+2 < len(s) && s[i] == '%' && isHexDigit(s[i+1]) && isHexDigit(s[i+2])
+}
+
+// idchar = ALPHA / DIGIT / "." / "-" / "_" / pct-encoded (pct-encoded handled by the caller)
+func isIDChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '.' || c == '-' || c == '_'
+}
+
+// pchar = unreserved / pct-encoded / sub-delims / ":" / "@" (pct-encoded handled by the caller)
+func isPChar(c byte) bool {
+	return isIDChar(c) || c == '~' || strings.IndexByte("!$&'()*+,;=", c) >= 0 || c == ':' || c == '@'
+}
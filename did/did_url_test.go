@@ -0,0 +1,83 @@
+package did
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDIDURL(t *testing.T) {
+	s := "did:web:mail.io:users:alice/path?service=didcomm&versionId=3&relativeRef=%2Finbox#key-1"
+	u, err := ParseDIDURL(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "web", u.DID.Protocol())
+	assert.Equal(t, "mail.io:users:alice", u.DID.Value())
+	assert.Equal(t, "did:web:mail.io:users:alice", u.DID.String())
+	assert.Equal(t, "/path", u.Path)
+	assert.Equal(t, "didcomm", u.Service())
+	assert.Equal(t, "3", u.VersionID())
+	assert.Equal(t, "/inbox", u.RelativeRef())
+	assert.Equal(t, "key-1", u.Fragment)
+	assert.Equal(t, s, u.String())
+
+	did, err := ParseDID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "mail.io:users:alice", did.Value())
+	assert.Equal(t, "key-1", did.Fragment())
+}
+
+func TestDIDURLRoundTrip(t *testing.T) {
+	for _, s := range []string{
+		"did:x:y",
+		"did:x:y?",
+		"did:x:y#",
+		"did:x:y?#",
+		"did:x:y/?#key",
+		"did:x:y?service=a#",
+	} {
+		u, err := ParseDIDURL(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, s, u.String())
+	}
+}
+
+func TestParseDIDURLVersionTime(t *testing.T) {
+	u, err := ParseDIDURL("did:mailio:0x1234?versionTime=2023-01-02T03:04:05Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vt, ok := u.VersionTime()
+	assert.True(t, ok)
+	assert.Equal(t, 2023, vt.Year())
+	assert.Equal(t, VersionSelector{VersionTime: vt}, u.Version())
+	assert.Equal(t, "versionTime=2023-01-02T03%3A04%3A05Z", u.Version().String())
+
+	_, err = ParseDIDURL("did:mailio:0x1234?versionTime=yesterday")
+	assert.ErrorIs(t, err, ErrInvalidDID)
+}
+
+func TestParseDIDURLInvalid(t *testing.T) {
+	invalid := []string{
+		"did:Mailio:1234",
+		"did:mailio:",
+		"did:mailio:12:",
+		"did:mailio:12 34",
+		"did:web:mail.io%3",
+		"did:web:mail.io/pa th",
+		"did:web:mail.io#frag#ment",
+		"did::1234",
+	}
+	for _, s := range invalid {
+		_, err := ParseDIDURL(s)
+		if !errors.Is(err, ErrInvalidDID) {
+			t.Fatalf("expected %q to be invalid, got %v", s, err)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package did
 
 import (
+	"crypto"
 	"crypto/ed25519"
+	"fmt"
 	"strconv"
 
-	"github.com/lestrrat-go/jwx/v2/jwk"
-	"github.com/mr-tron/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
 
 const (
@@ -13,7 +14,42 @@ const (
 	MessagingDIDType      = "DIDCommMessaging"
 )
 
-func NewMailioDIDDocument(mk *MailioKey, mailioPublicKey ed25519.PublicKey, AuthServiceEndpoint string, MessageServiceEndpoint string) (*Document, error) {
+// DocumentOption customizes the document created by NewMailioDIDDocument
+type DocumentOption func(*documentOptions)
+
+type documentOptions struct {
+	deriveKeyAgreement     bool
+	verificationMethodType string
+}
+
+// WithDerivedKeyAgreement derives the X25519 key agreement method from the Ed25519 MasterSignKey
+// when MailioKey.MasterAgreementKey is nil
+func WithDerivedKeyAgreement() DocumentOption {
+	return func(o *documentOptions) {
+		o.deriveKeyAgreement = true
+	}
+}
+
+// WithVerificationMethodType selects the verification method type keys are published as:
+// PublicKeyJwkType (default), KeyTypeEd25519 (Ed25519VerificationKey2020) or KeyTypeMultikey, the latter two
+// with publicKeyMultibase. Ed25519VerificationKey2020 only applies to ed25519 keys, other keys stay JsonWebKey2020.
+func WithVerificationMethodType(vmType string) DocumentOption {
+	return func(o *documentOptions) {
+		o.verificationMethodType = vmType
+	}
+}
+
+// NewMailioDIDDocument is the DocumentBuilder preset of a did:mailio document: the master key (authentication),
+// the master agreement key, verification keys (assertionMethod), embedded authentication keys and the
+// Mailio auth and DIDComm messaging services of the Mailio server identified by mailioPublicKey
+func NewMailioDIDDocument(mk *MailioKey, mailioPublicKey crypto.PublicKey, AuthServiceEndpoint string, MessageServiceEndpoint string, opts ...DocumentOption) (*Document, error) {
+	options := &documentOptions{
+		verificationMethodType: PublicKeyJwkType,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
 	did, err := mk.DIDFromKey()
 	if err != nil {
 		return nil, err
@@ -30,110 +66,106 @@ func NewMailioDIDDocument(mk *MailioKey, mailioPublicKey ed25519.PublicKey, Auth
 		return nil, mErr
 	}
 
+	b := NewDocumentBuilder(did).
+		AddContext(CtxSecEd25519_2020v1, CtxSecX25519_2019v1).
+		SetVerificationMethodType(options.verificationMethodType)
+
 	// A set of parameters that can be used together with a process to independently verify a proof.
 	// For example, a cryptographic public key can be used as a verification method with respect to a digital signature;
 	// in such usage, it verifies that the signer possessed the associated cryptographic private key.
-	verificationMethods := make([]VerificationMethod, 0)
+	// The authentication verification relationship is used to specify how the DID subject is expected to be authenticated,
+	// for purposes such as logging into a website or engaging in any sort of challenge-response protocol.
+	// default auth method uses master key to prove ownership
+	b.AddKey(did.String()+MasterKeyFragment, mk.MasterSignKey, RelationshipAuthentication)
 
-	// add master key in there
-	if mk.MasterSignKey != nil {
-		rawKey, rkErr := jwk.FromRaw(mk.MasterSignKey.PublicKey)
-		if rkErr != nil {
-			return nil, rkErr
+	// KeyAgreement in DID is used to specify the cryptographic key exhange algorithm between two parties
+	masterAgreementKey := mk.MasterAgreementKey
+	if masterAgreementKey == nil && options.deriveKeyAgreement {
+		edKey, ok := mk.MasterSignKey.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: key agreement can only be derived from ed25519 master key", ErrUnsupportedKeyType)
 		}
-		pk := &PublicKeyJwk{
-			Key: rawKey.(jwk.Key),
+		derived, dErr := Ed25519PublicKeyToX25519(edKey)
+		if dErr != nil {
+			return nil, dErr
 		}
-		verificationMethod := VerificationMethod{
-			Type:         PublicKeyJwkType,
-			Controller:   did.String(),
-			PublicKeyJwk: pk,
-			ID:           did.String() + "#master",
+		masterAgreementKey = &Key{
+			Type:      KeyTypeX25519KeyAgreement,
+			PublicKey: derived,
 		}
-		verificationMethods = append(verificationMethods, verificationMethod)
+	}
+	if masterAgreementKey != nil {
+		b.AddAgreementKey(did.String()+AgreementKeyFragment, masterAgreementKey)
 	}
 
-	// KeyAgreement in DID is used to specify the cryptographic key exhange algorithm between two parties
-	keyAgreements := make([]KeyAgreement, 0)
-	if mk.MasterAgreementKey != nil {
-		agreementMethod := KeyAgreement{
-			Type:               KeyTypeX25519KeyAgreement,
-			Controller:         did.String(),
-			PublicKeyMultibase: base58.Encode(mk.MasterAgreementKey.PublicKey),
-			ID:                 did.String(),
-		}
-		keyAgreements = append(keyAgreements, agreementMethod)
-	}
-
-	if len(mk.VerificationKeys) > 0 {
-		for i, vk := range mk.VerificationKeys {
-			rawKey, rkErr := jwk.FromRaw(vk.PublicKey)
-			if rkErr != nil {
-				return nil, rkErr
-			}
-
-			pk := &PublicKeyJwk{
-				Key: rawKey.(jwk.Key),
-			}
-			verificationMethod := VerificationMethod{
-				Type:         PublicKeyJwkType,
-				Controller:   did.String(),
-				PublicKeyJwk: pk,
-				ID:           "#" + strconv.Itoa(i+1),
-			}
-			verificationMethods = append(verificationMethods, verificationMethod)
-		}
+	// The assertionMethod verification relationship is used to specify how the DID subject is expected to express claims,
+	// such as for the purposes of issuing a Verifiable Credential.
+	for i, vk := range mk.VerificationKeys {
+		b.AddKey("#"+strconv.Itoa(i+1), vk, RelationshipAssertionMethod)
+	}
+	for i, vk := range mk.AuthenticationKeys {
+		b.AddEmbeddedKey(RelationshipAuthentication, did.String()+"#auth-"+strconv.Itoa(i+1), vk)
 	}
 
-	// The authentication verification relationship is used to specify how the DID subject is expected to be authenticated,
-	// for purposes such as logging into a website or engaging in any sort of challenge-response protocol.
-	authMethods := make([]interface{}, 0)
-	authMethod := did.String() + "#master"
-	// default auth method uses master key to prove ownership
-	authMethods = append(authMethods, authMethod)
-
-	if len(mk.AuthenticationKeys) > 0 {
-		for _, vk := range mk.AuthenticationKeys {
-			rawKey, rkErr := jwk.FromRaw(vk.PublicKey)
-			if rkErr != nil {
-				return nil, rkErr
-			}
-
-			pk := &PublicKeyJwk{
-				Key: rawKey.(jwk.Key),
-			}
-			authMethod := VerificationMethod{
-				Type:         PublicKeyJwkType,
-				Controller:   did.String(),
-				PublicKeyJwk: pk,
-			}
-			authMethods = append(authMethods, authMethod)
+	return b.
+		AddService(Service{
+			ID:              mailioDid.String() + "#auth",
+			Type:            AuthenticationDIDType,
+			ServiceEndpoint: AuthServiceEndpoint,
+		}).
+		AddService(Service{
+			ID:              mailioDid.String() + "#didcomm",
+			Type:            MessagingDIDType,
+			ServiceEndpoint: MessageServiceEndpoint,
+			Accept:          []string{"didcomm/v2", "didcomm/aip2;env=rfc587"},
+		}).
+		Build()
+}
+
+// newKeyVerificationMethod publishes the key as a verification method. Key.Type of KeyTypeMultikey or
+// KeyTypeEcdsaSecp256k1_2019 is always honored, other keys are published as vmType.
+func newKeyVerificationMethod(id string, controller string, key *Key, vmType string) (VerificationMethod, error) {
+	vm := VerificationMethod{
+		ID:         id,
+		Controller: controller,
+	}
+	if key.Type == KeyTypeMultikey || key.Type == KeyTypeEcdsaSecp256k1_2019 {
+		vmType = key.Type
+	}
+	switch vmType {
+	case KeyTypeEd25519:
+		ek, ok := key.PublicKey.(ed25519.PublicKey)
+		if !ok {
+			break
+		}
+		multibaseKey, err := EncodePublicKeyMultibase(ek)
+		if err != nil {
+			return vm, err
+		}
+		vm.Type = KeyTypeEd25519
+		vm.PublicKeyMultibase = multibaseKey
+		return vm, nil
+	case KeyTypeMultikey:
+		multibaseKey, err := EncodePublicKeyMultibase(key.PublicKey)
+		if err != nil {
+			return vm, err
 		}
+		vm.Type = KeyTypeMultikey
+		vm.PublicKeyMultibase = multibaseKey
+		return vm, nil
+	case KeyTypeEcdsaSecp256k1_2019:
+		if _, ok := key.PublicKey.(*secp256k1.PublicKey); !ok {
+			return vm, fmt.Errorf("%w: %s requires a secp256k1 key, got %T", ErrUnsupportedKeyType, key.Type, key.PublicKey)
+		}
+		vm.Type = KeyTypeEcdsaSecp256k1_2019
 	}
-
-	doc := &Document{
-		Context: []string{
-			CtxDIDv1,
-			CtxSecEd25519_2020v1,
-			CtxSecX25519_2019v1,
-		},
-		ID:                 did,
-		VerificationMethod: verificationMethods,
-		Authentication:     authMethods,
-		Service: []Service{
-			{
-				ID:              mailioDid.String() + "#auth",
-				Type:            AuthenticationDIDType,
-				ServiceEndpoint: AuthServiceEndpoint,
-			},
-			{
-				ID:              mailioDid.String() + "#didcomm",
-				Type:            MessagingDIDType,
-				ServiceEndpoint: MessageServiceEndpoint,
-				Accept:          []string{"didcomm/v2", "didcomm/aip2;env=rfc587"},
-			},
-		},
-		KeyAgreement: keyAgreements,
+	if vm.Type == "" {
+		vm.Type = PublicKeyJwkType
+	}
+	pk, err := publicKeyToJwk(key.PublicKey)
+	if err != nil {
+		return vm, err
 	}
-	return doc, nil
+	vm.PublicKeyJwk = pk
+	return vm, nil
 }
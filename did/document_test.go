@@ -1,9 +1,17 @@
 package did
 
 import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/mr-tron/base58"
+	"github.com/stretchr/testify/assert"
 )
 
 const (
@@ -17,7 +25,7 @@ func TestNewDocument(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	pub, _, ppErr := CreateX25519ECDSAKeys()
+	pub, _, ppErr := CreateX25519Keys()
 	if ppErr != nil {
 		t.Fatal(ppErr)
 	}
@@ -38,3 +46,85 @@ func TestNewDocument(t *testing.T) {
 	}
 	fmt.Printf("document: %s\n", mshld)
 }
+
+func TestNewDocumentDerivedKeyAgreement(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	serverMk, _ := GenerateMailioPublicKeys()
+	mk.MasterAgreementKey = nil
+
+	doc, err := NewMailioDIDDocument(mk, serverMk.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, doc.KeyAgreement)
+
+	doc, err = NewMailioDIDDocument(mk, serverMk.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint, WithDerivedKeyAgreement())
+	if err != nil {
+		t.Fatal(err)
+	}
+	xk, err := doc.KeyAgreement[0].ECDHPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := Ed25519PublicKeyToX25519(mk.MasterSignKey.PublicKey.(ed25519.PublicKey))
+	assert.True(t, expected.Equal(xk))
+	assert.NoError(t, doc.Validate())
+}
+
+func TestNewDocumentVerificationMethodType(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	serverMk, _ := GenerateMailioPublicKeys()
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	mk.VerificationKeys = []*Key{{Type: PublicKeyJwkType, PublicKey: &p256.PublicKey}}
+
+	for _, vmType := range []string{KeyTypeEd25519, KeyTypeMultikey} {
+		doc, err := NewMailioDIDDocument(mk, serverMk.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint, WithVerificationMethodType(vmType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NoError(t, doc.Validate())
+
+		b, _ := json.Marshal(doc)
+		var parsed Document
+		if err := json.Unmarshal(b, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		master, _ := parsed.FindVerificationMethod(MasterKeyFragment)
+		assert.Equal(t, vmType, master.Type)
+		assert.Nil(t, master.PublicKeyJwk)
+		assert.True(t, strings.HasPrefix(master.PublicKeyMultibase, "z6Mk"))
+		did, _ := mk.DIDFromKey()
+		assert.NoError(t, VerifyMailioDocument(&parsed, did))
+
+		// Ed25519VerificationKey2020 can't hold a P-256 key
+		p256Method, _ := parsed.FindVerificationMethod("#1")
+		if vmType == KeyTypeEd25519 {
+			assert.Equal(t, PublicKeyJwkType, p256Method.Type)
+		} else {
+			assert.Equal(t, KeyTypeMultikey, p256Method.Type)
+			assert.Contains(t, parsed.Context, CtxSecMultikeyV1)
+		}
+		pk, err := p256Method.GetPublicKey()
+		assert.NoError(t, err)
+		assert.True(t, p256.PublicKey.Equal(*pk))
+	}
+
+	_, err := NewMailioDIDDocument(mk, serverMk.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint, WithVerificationMethodType(KeyTypeEd25519_2018))
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
+
+func TestVerificationMethodBase58(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	vm := VerificationMethod{
+		ID:              "#1",
+		Type:            KeyTypeEd25519_2018,
+		PublicKeyBase58: base58.Encode(pub),
+	}
+	pk, err := vm.GetPublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(pub), *pk)
+
+	vm.Type = PublicKeyJwkType
+	_, err = vm.GetPublicKey()
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
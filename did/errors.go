@@ -0,0 +1,86 @@
+package did
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DID Resolution error codes as defined by https://www.w3.org/TR/did-core/#did-resolution-metadata
+const (
+	ErrCodeInvalidDID                 = "invalidDid"
+	ErrCodeNotFound                   = "notFound"
+	ErrCodeRepresentationNotSupported = "representationNotSupported"
+	ErrCodeMethodNotSupported         = "methodNotSupported"
+	// ErrCodeInternalError is reported when the resolution failed for a reason unrelated to the DID,
+	// e.g. an unexpected response of the server hosting the document
+	ErrCodeInternalError = "internalError"
+	// ErrCodeInvalidDIDDocument is reported when the DID document found (or its operation log) fails verification
+	// or can't be processed, e.g. because it's too large
+	ErrCodeInvalidDIDDocument = "invalidDidDocument"
+)
+
+var (
+	// ErrInvalidDID is returned when a DID or DID URL is not conformant with the DID syntax
+	ErrInvalidDID = &ResolutionError{Code: ErrCodeInvalidDID}
+	// ErrNotFound is returned when the resolver was unable to find the DID document
+	ErrNotFound = &ResolutionError{Code: ErrCodeNotFound}
+	// ErrRepresentationNotSupported is returned when the requested representation (content type) is not supported
+	ErrRepresentationNotSupported = &ResolutionError{Code: ErrCodeRepresentationNotSupported}
+	// ErrMethodNotSupported is returned when no resolver supports the DID method
+	ErrMethodNotSupported = &ResolutionError{Code: ErrCodeMethodNotSupported}
+	// ErrInternalError is returned when the resolution failed for a reason unrelated to the DID
+	ErrInternalError = &ResolutionError{Code: ErrCodeInternalError}
+	// ErrInvalidDIDDocument is returned when the resolver found a DID document or operation log it can't accept
+	ErrInvalidDIDDocument = &ResolutionError{Code: ErrCodeInvalidDIDDocument}
+
+	// ErrInvalidDocument is returned when a DID document fails verification
+	ErrInvalidDocument = errors.New("invalid did document")
+	// ErrInvalidOperation is returned when an operation of a did:mailio operation log fails verification
+	ErrInvalidOperation = errors.New("invalid did operation")
+	// ErrConflict is returned when an operation doesn't extend the current end of the operation log,
+	// e.g. because another operation was published concurrently
+	ErrConflict = errors.New("operation log conflict")
+	// ErrDeactivated is returned when keys of a deactivated DID are used
+	ErrDeactivated = errors.New("did deactivated")
+)
+
+// ResolutionError is a DID Resolution error carrying one of the standard error codes.
+// Use errors.Is with one of the Err* variables to check for a specific code:
+//
+//	if errors.Is(err, did.ErrNotFound) { ... }
+type ResolutionError struct {
+	Code    string
+	Message string
+}
+
+func (e *ResolutionError) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is reports whether target is a ResolutionError with the same code
+func (e *ResolutionError) Is(target error) bool {
+	t, ok := target.(*ResolutionError)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code
+}
+
+// ErrorCode returns the DID Resolution error code of err or an empty string if err isn't a ResolutionError
+func ErrorCode(err error) string {
+	var re *ResolutionError
+	if errors.As(err, &re) {
+		return re.Code
+	}
+	return ""
+}
+
+func newResolutionError(code string, format string, args ...interface{}) error {
+	return &ResolutionError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
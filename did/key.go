@@ -1,6 +1,7 @@
 package did
 
 import (
+	"crypto"
 	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/base64"
@@ -9,10 +10,24 @@ import (
 )
 
 const (
-	MCed25519 = 0xED
+	// multicodec codes of public keys (https://github.com/multiformats/multicodec/blob/master/table.csv)
+	MCed25519   = 0xED
+	MCx25519    = 0xEC
+	MCsecp256k1 = 0xE7
+	MCp256      = 0x1200
+	MCp384      = 0x1201
+	MCrsa       = 0x1205
 
 	KeyTypeEd25519 = "Ed25519VerificationKey2020"
 
+	KeyTypeEd25519_2018 = "Ed25519VerificationKey2018"
+
+	KeyTypeEcdsaSecp256k1Recovery2020 = "EcdsaSecp256k1RecoveryMethod2020"
+
+	KeyTypeEcdsaSecp256k1_2019 = "EcdsaSecp256k1VerificationKey2019"
+
+	KeyTypeMultikey = "Multikey"
+
 	PublicKeyJwkType = "JsonWebKey2020"
 
 	KeyTypeX25519KeyAgreement = "X25519KeyAgreementKey2019"
@@ -23,24 +38,28 @@ const (
 var (
 	ErrInvalidSignature = fmt.Errorf("invalid signature")
 
-	mcToType = map[uint64]string{
-		MCed25519: KeyTypeEd25519,
-	}
+	ErrKeyNotFound = fmt.Errorf("key not found")
 
-	typeToMc = map[string]uint64{
-		KeyTypeEd25519: MCed25519,
-	}
+	ErrUnsupportedKeyType = fmt.Errorf("unsupported key type")
 )
 
 type MailioKey struct {
-	MasterSignKey      *Key
+	// MasterSignKey is the current master key
+	MasterSignKey *Key
+	// InceptionKey is the master key the DID was derived from, nil until the master key has been rotated
+	InceptionKey       *Key
 	MasterAgreementKey *Key
 	VerificationKeys   []*Key
 	AuthenticationKeys []*Key
 }
 
+// Key is a public key of a Mailio identity.
+// PublicKey holds ed25519.PublicKey, *ecdsa.PublicKey (P-256, P-384), *secp256k1.PublicKey or *rsa.PublicKey
+// for signing keys and X25519 *ecdh.PublicKey for key agreement keys.
+// Type selects the verification method type the key is published as (JsonWebKey2020 unless
+// KeyTypeEcdsaSecp256k1_2019 or KeyTypeMultikey).
 type Key struct {
-	PublicKey ed25519.PublicKey
+	PublicKey crypto.PublicKey
 	Type      string
 }
 
@@ -48,12 +67,22 @@ func (k *MailioKey) KeyType() string {
 	return k.MasterSignKey.Type
 }
 
+// InceptionSignKey returns the master key the DID is derived from: InceptionKey after a rotation, MasterSignKey otherwise
+func (k *MailioKey) InceptionSignKey() *Key {
+	if k.InceptionKey != nil {
+		return k.InceptionKey
+	}
+	return k.MasterSignKey
+}
+
+// DIDFromKey derives the DID from the inception master key, so the DID stays stable when the master key is rotated
 func (k *MailioKey) DIDFromKey() (DID, error) {
-	if k.MasterSignKey == nil {
-		return DID{}, fmt.Errorf("master key required")
+	address, err := k.MailioAddress()
+	if err != nil {
+		return DID{}, err
 	}
 
-	didStr := DIDKeyPrefix + k.MailioAddress()
+	didStr := DIDKeyPrefix + address
 
 	id, err := ParseDID(didStr)
 	if err != nil {
@@ -65,15 +94,75 @@ func (k *MailioKey) DIDFromKey() (DID, error) {
 
 }
 
-func (k *MailioKey) DID() string {
-	return DIDKeyPrefix + k.MailioAddress()
+// DID returns the did:mailio of the key as string
+func (k *MailioKey) DID() (string, error) {
+	address, err := k.MailioAddress()
+	if err != nil {
+		return "", err
+	}
+	return DIDKeyPrefix + address, nil
 }
 
-func (k *MailioKey) MailioAddress() string {
+// MailioAddress is derived from the inception master key. Ed25519 keys are hashed as raw bytes,
+// EC and RSA keys in their multicodec (compressed point, PKCS #1) form.
+// Fails with ErrUnsupportedKeyType for keys without a supported encoding.
+func (k *MailioKey) MailioAddress() (string, error) {
+	if k.InceptionSignKey() == nil {
+		return "", fmt.Errorf("master key required")
+	}
+	pubKey, err := publicKeyBytes(k.InceptionSignKey().PublicKey)
+	if err != nil {
+		return "", err
+	}
 	hasher := sha256.New()
-	pubKey := k.MasterSignKey.PublicKey
 	b64Encoded := base64.StdEncoding.EncodeToString(pubKey)
 	hasher.Write([]byte(b64Encoded))
 	sha256Key := hex.EncodeToString(hasher.Sum(nil))
-	return "0x" + sha256Key[64-40:64]
+	return "0x" + sha256Key[64-40:64], nil
+}
+
+// EncodePublicKeyMultibase encodes the public key as a base58btc multibase, multicodec prefixed value
+// as used by publicKeyMultibase (e.g. z6Mk... for ed25519 and z6LS... for x25519)
+func EncodePublicKeyMultibase(publicKey crypto.PublicKey) (string, error) {
+	code, raw, err := multicodecPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return encodeMultibaseBase58(MulticodecEncode(code, raw)), nil
+}
+
+// DecodePublicKeyMultibase decodes a multibase, multicodec prefixed public key in any supported multibase encoding
+func DecodePublicKeyMultibase(value string) (crypto.PublicKey, error) {
+	decoded, err := decodeMultibase(value)
+	if err != nil {
+		return nil, err
+	}
+	code, raw, err := MulticodecDecode(decoded)
+	if err != nil {
+		return nil, err
+	}
+	return parseMulticodecPublicKey(code, raw)
+}
+
+// NextKeyCommitment returns the pre-rotation commitment to the next master key: the base58btc multibase encoded
+// sha2-256 multihash of the multicodec prefixed public key. The key itself stays secret until it's revealed by a
+// rotation operation.
+func NextKeyCommitment(publicKey crypto.PublicKey) (string, error) {
+	code, raw, err := multicodecPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return multihashSHA256(MulticodecEncode(code, raw)), nil
+}
+
+// publicKeyBytes returns the raw bytes of the public key
+func publicKeyBytes(publicKey crypto.PublicKey) ([]byte, error) {
+	switch k := publicKey.(type) {
+	case ed25519.PublicKey:
+		return k, nil
+	case []byte:
+		return k, nil
+	}
+	_, raw, err := multicodecPublicKey(publicKey)
+	return raw, err
 }
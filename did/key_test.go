@@ -3,14 +3,22 @@ package did
 import (
 	"bytes"
 	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/crypto/curve25519"
 )
 
 // test keys
@@ -35,7 +43,7 @@ func GenerateMailioPublicKeys() (*MailioKey, error) {
 	ret.MasterSignKey.PublicKey = edpriv.Public().(ed25519.PublicKey)
 
 	// master agreement key
-	pub, _, pkErr := CreateX25519ECDSAKeys()
+	pub, _, pkErr := CreateX25519Keys()
 	if pkErr != nil {
 		return nil, pkErr
 	}
@@ -44,23 +52,13 @@ func GenerateMailioPublicKeys() (*MailioKey, error) {
 	return ret, nil
 }
 
-// only for testing purposes, otherwise this is created by
-func CreateX25519ECDSAKeys() (ed25519.PublicKey, ed25519.PrivateKey, error) {
-	privateKey := make([]byte, curve25519.ScalarSize)
-	if _, err := rand.Read(privateKey); err != nil {
-		return nil, nil, err
-	}
-
-	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+// only for testing purposes, otherwise this is created by the key holder
+func CreateX25519Keys() (*ecdh.PublicKey, *ecdh.PrivateKey, error) {
+	privateKey, err := ecdh.X25519().GenerateKey(rand.Reader)
 	if err != nil {
 		return nil, nil, err
 	}
-	pk := ed25519.PublicKey(publicKey)
-	pr := ed25519.PrivateKey(privateKey)
-	// pk := crypto.PublicKey(publicKey)
-	// pr := crypto.PrivateKey(privateKey)
-
-	return pk, pr, nil
+	return privateKey.PublicKey(), privateKey, nil
 }
 
 func TestDIDFromKey(t *testing.T) {
@@ -72,8 +70,25 @@ func TestDIDFromKey(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	expected := mk.MailioAddress()
+	expected, err := mk.MailioAddress()
+	assert.NoError(t, err)
 	assert.Equal(t, expected, did.Value())
+
+	// keys without a supported encoding have no address
+	unsupported := &MailioKey{MasterSignKey: &Key{PublicKey: "not a key"}}
+	_, err = unsupported.MailioAddress()
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+	_, err = unsupported.DID()
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
+
+// mailioDID returns the did:mailio of the key
+func mailioDID(t *testing.T, mk *MailioKey) string {
+	did, err := mk.DID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return did
 }
 
 func TestGetValidationMethodPublicKeys(t *testing.T) {
@@ -92,7 +107,7 @@ func TestGetValidationMethodPublicKeys(t *testing.T) {
 		t.Fatal(err)
 	}
 	keyOne := ed25519.PublicKey((*masterVerificationPublicKey).([]byte))
-	if !bytes.Equal(keyOne, mk.MasterSignKey.PublicKey) {
+	if !bytes.Equal(keyOne, mk.MasterSignKey.PublicKey.(ed25519.PublicKey)) {
 		t.Fatal("master verification key is not equal")
 	}
 }
@@ -112,9 +127,9 @@ func TestGetKeyAgreementPublicKey(t *testing.T) {
 		if kaErr != nil {
 			t.Fatal(kaErr)
 		}
-		keyOne := (*kaPublicKey).([]byte)
-		keyTwo := mk.MasterAgreementKey.PublicKey
-		if !bytes.Equal(keyOne, keyTwo) {
+		keyOne := (*kaPublicKey).(*ecdh.PublicKey)
+		keyTwo := mk.MasterAgreementKey.PublicKey.(*ecdh.PublicKey)
+		if !keyOne.Equal(keyTwo) {
 			t.Fatal("key agreement key is not equal")
 		}
 	}
@@ -125,22 +140,16 @@ func TestAuthenticationPublicKey(t *testing.T) {
 	mkMailio, _ := GenerateMailioPublicKeys()
 	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
 
-	var authPublicKey *crypto.PublicKey
-	for _, auth := range doc.Authentication {
-		switch v := auth.(type) {
-		case string:
-			apk, _ := doc.GetVerificationPublicKey(v)
-			authPublicKey = apk
-			break
-		case map[string]interface{}:
-			t.Fatal("shouldn't be here")
-			break
-		default:
-			t.Fatal("shouldn't be here")
-		}
+	methods, err := doc.AuthenticationMethods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authPublicKey, err := methods[0].GetPublicKey()
+	if err != nil {
+		t.Fatal(err)
 	}
 	keyOne := (*authPublicKey).([]byte)
-	keyTwo := mk.MasterSignKey.PublicKey
+	keyTwo := mk.MasterSignKey.PublicKey.(ed25519.PublicKey)
 	if !bytes.Equal(keyOne, keyTwo) {
 		t.Fatal("authentication key is not equal")
 	}
@@ -156,3 +165,110 @@ func TestCryptoPublicKeyType(t *testing.T) {
 	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
 	fmt.Printf("%+v\n", doc)
 }
+
+func TestVerificationPublicKeyNotFound(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	_, err := doc.GetVerificationPublicKey(mailioDID(t, mk) + "#missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestMultiAlgorithmKeys(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	k1, _ := secp256k1.GeneratePrivateKey()
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	mk.VerificationKeys = []*Key{
+		{Type: PublicKeyJwkType, PublicKey: &p256.PublicKey},
+		{Type: PublicKeyJwkType, PublicKey: &p384.PublicKey},
+		{Type: PublicKeyJwkType, PublicKey: &rsaKey.PublicKey},
+		{Type: KeyTypeEcdsaSecp256k1_2019, PublicKey: k1.PubKey()},
+		{Type: KeyTypeMultikey, PublicKey: &p256.PublicKey},
+		{Type: KeyTypeMultikey, PublicKey: edPub},
+	}
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, doc.Validate())
+
+	b, _ := json.Marshal(doc)
+	var parsed Document
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, KeyTypeEcdsaSecp256k1_2019, parsed.VerificationMethod[4].Type)
+	assert.Equal(t, KeyTypeMultikey, parsed.VerificationMethod[5].Type)
+	assert.True(t, strings.HasPrefix(parsed.VerificationMethod[5].PublicKeyMultibase, "zDn"))
+
+	for i, vk := range mk.VerificationKeys {
+		pk, pkErr := parsed.GetVerificationPublicKey("#" + strconv.Itoa(i+1))
+		if pkErr != nil {
+			t.Fatal(pkErr)
+		}
+		switch expected := vk.PublicKey.(type) {
+		case ed25519.PublicKey:
+			assert.Equal(t, []byte(expected), *pk)
+		case *secp256k1.PublicKey:
+			assert.True(t, expected.IsEqual((*pk).(*secp256k1.PublicKey)))
+		default:
+			assert.True(t, expected.(interface{ Equal(crypto.PublicKey) bool }).Equal(*pk))
+		}
+	}
+
+	// a P-256 master key derives a stable address as well
+	mk.MasterSignKey = &Key{Type: PublicKeyJwkType, PublicKey: &p256.PublicKey}
+	did, err := mk.DIDFromKey()
+	assert.NoError(t, err)
+	doc, _ = NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	assert.NoError(t, VerifyMailioDocument(doc, did))
+
+	mk.VerificationKeys = []*Key{{Type: KeyTypeEcdsaSecp256k1_2019, PublicKey: &p256.PublicKey}}
+	_, err = NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
+
+func TestKeyAgreementECDH(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	pub, priv, _ := CreateX25519Keys()
+	mk.MasterAgreementKey = &Key{Type: KeyTypeX25519KeyAgreement, PublicKey: pub}
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(doc)
+	var parsed Document
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	ka, err := parsed.FindKeyAgreement(AgreementKeyFragment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, ka.PublicKeyMultibase)
+	assert.Equal(t, "X25519", ka.PublicKeyJwk.Key.(jwk.OKPPublicKey).Crv().String())
+
+	xk, err := ka.ECDHPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, senderPriv, _ := CreateX25519Keys()
+	sent, err := senderPriv.ECDH(xk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received, _ := priv.ECDH(senderPriv.PublicKey())
+	assert.Equal(t, sent, received)
+
+	// multibase only (documents created before publicKeyJwk was emitted)
+	ka.PublicKeyJwk = nil
+	legacy, err := ka.ECDHPublicKey()
+	assert.NoError(t, err)
+	assert.True(t, legacy.Equal(pub))
+}
@@ -0,0 +1,129 @@
+package did
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+const (
+	DIDMethodMailio = "mailio"
+
+	// fragment of the master key verification method in did:mailio documents
+	MasterKeyFragment = "#master"
+	// fragment of the master key agreement method in did:mailio documents
+	AgreementKeyFragment = "#agreement"
+)
+
+// MailioResolver resolves did:mailio DIDs from a DocumentStore.
+// Since the Mailio address is a hash of the master key, every document read from the store
+// is verified to belong to the DID before it's returned. When the store implements OperationLogStore
+// the document is the result of replaying the verified operation log; deactivated DIDs resolve to the
+// NewTombstoneDocument with DocumentMetadata.Deactivated set.
+type MailioResolver struct {
+	Store DocumentStore
+}
+
+// NewMailioResolver creates a did:mailio resolver backed by the store
+func NewMailioResolver(store DocumentStore) *MailioResolver {
+	return &MailioResolver{
+		Store: store,
+	}
+}
+
+func (r *MailioResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	if did.Protocol() != DIDMethodMailio {
+		return nil, &ResolutionMetadata{Error: ErrCodeMethodNotSupported}, nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:mailio: %s", did.String())
+	}
+	if !mailioAddressRegex.MatchString(did.Value()) {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDID}, nil, newResolutionError(ErrCodeInvalidDID, "invalid mailio address: %q", did.Value())
+	}
+	if logStore, ok := r.Store.(OperationLogStore); ok {
+		log, err := logStore.GetOperations(ctx, did.Value())
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+		}
+		if len(log) > 0 {
+			doc, md, err := log.Replay(did)
+			if err != nil {
+				return nil, &ResolutionMetadata{Error: ErrCodeInvalidDIDDocument}, nil, invalidDIDDocumentError(err)
+			}
+			return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, md, nil
+		}
+	}
+
+	// documents stored without an operation log
+	doc, err := r.Store.Get(ctx, did.Value())
+	if err != nil {
+		return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+	}
+	if err := VerifyMailioDocument(doc, did); err != nil {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDIDDocument}, nil, invalidDIDDocumentError(err)
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+}
+
+// ResolveVersion resolves a historical version of the document from the operation log of the store (GetDocumentVersion)
+func (r *MailioResolver) ResolveVersion(ctx context.Context, did DID, version VersionSelector) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	if version.IsZero() {
+		return r.Resolve(ctx, did)
+	}
+	if did.Protocol() != DIDMethodMailio {
+		return nil, &ResolutionMetadata{Error: ErrCodeMethodNotSupported}, nil, newResolutionError(ErrCodeMethodNotSupported, "not a did:mailio: %s", did.String())
+	}
+	if !mailioAddressRegex.MatchString(did.Value()) {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDID}, nil, newResolutionError(ErrCodeInvalidDID, "invalid mailio address: %q", did.Value())
+	}
+	doc, md, err := GetDocumentVersion(ctx, r.Store, did, version)
+	if err != nil {
+		return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+	}
+	return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, md, nil
+}
+
+// invalidDIDDocumentError reports the verification failure of a stored document or operation log as invalidDidDocument,
+// the returned error matches both ErrInvalidDIDDocument and err
+func invalidDIDDocumentError(err error) error {
+	return fmt.Errorf("%w: %w", ErrInvalidDIDDocument, err)
+}
+
+// VerifyMailioDocument checks that the document belongs to the did:mailio: the document ID must equal the DID
+// and the master key of the document must hash to the Mailio address (DID.Value()).
+// Documents with a rotated master key can only be verified by replaying their operation log (OperationLog.Replay).
+func VerifyMailioDocument(doc *Document, did DID) error {
+	expected := "did:" + did.Protocol() + ":" + did.Value()
+	if doc.ID.String() != expected {
+		return fmt.Errorf("%w: document id %q does not match %q", ErrInvalidDocument, doc.ID.String(), expected)
+	}
+	publicKey, err := documentMasterKey(doc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	mk := &MailioKey{
+		MasterSignKey: &Key{
+			PublicKey: publicKey,
+		},
+	}
+	address, err := mk.MailioAddress()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if address != did.Value() {
+		return fmt.Errorf("%w: master key does not hash to %s", ErrInvalidDocument, did.Value())
+	}
+	return nil
+}
+
+// documentMasterKey returns the public key of the master verification method (ed25519 keys as ed25519.PublicKey)
+func documentMasterKey(doc *Document) (crypto.PublicKey, error) {
+	masterKey, err := doc.GetVerificationPublicKey(MasterKeyFragment)
+	if err != nil {
+		return nil, err
+	}
+	if raw, ok := (*masterKey).([]byte); ok {
+		return ed25519.PublicKey(raw), nil
+	}
+	return *masterKey, nil
+}
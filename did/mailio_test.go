@@ -0,0 +1,68 @@
+package did
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailioResolver(t *testing.T) {
+	fileStore, err := NewFileDocumentStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, store := range []DocumentStore{NewMemoryDocumentStore(), fileStore} {
+		mk, _ := GenerateMailioPublicKeys()
+		mkMailio, _ := GenerateMailioPublicKeys()
+		doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Put(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+
+		resolver := NewMailioResolver(store)
+		resolved, _, _, err := resolver.Resolve(context.Background(), doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, doc.ID.String(), resolved.ID.String())
+
+		other, _ := GenerateMailioPublicKeys()
+		otherDID, _ := other.DIDFromKey()
+		_, _, _, err = resolver.Resolve(context.Background(), otherDID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	}
+}
+
+func TestMailioResolverRejectsForeignMasterKey(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	// replace the master key with a key that doesn't hash to the DID
+	attacker, _ := GenerateMailioPublicKeys()
+	attackerDoc, _ := NewMailioDIDDocument(attacker, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	doc.VerificationMethod[0].PublicKeyJwk = attackerDoc.VerificationMethod[0].PublicKeyJwk
+
+	store := NewMemoryDocumentStore()
+	store.Put(context.Background(), doc)
+	_, resMeta, _, err := NewMailioResolver(store).Resolve(context.Background(), doc.ID)
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+	assert.ErrorIs(t, err, ErrInvalidDIDDocument)
+	assert.Equal(t, ErrorCode(err), resMeta.Error)
+
+	// a corrupt operation log
+	store.AppendOperation(context.Background(), doc.ID.Value(), "", "not.a.jws", nil)
+	_, resMeta, _, err = NewMailioResolver(store).Resolve(context.Background(), doc.ID)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+	assert.False(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, ErrCodeInvalidDIDDocument, resMeta.Error)
+	_, resMeta, _, err = NewMailioResolver(store).ResolveVersion(context.Background(), doc.ID, VersionSelector{VersionTime: time.Now()})
+	assert.ErrorIs(t, err, ErrInvalidDIDDocument)
+	assert.Equal(t, ErrCodeInvalidDIDDocument, resMeta.Error)
+}
@@ -0,0 +1,120 @@
+package did
+
+import (
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// multibase prefixes (https://github.com/multiformats/multibase/blob/master/multibase.csv)
+const (
+	// MultibaseBase58BTC is the multibase prefix of base58btc encoded values
+	MultibaseBase58BTC = 'z'
+	// MultibaseBase64URL is the multibase prefix of unpadded base64url encoded values
+	MultibaseBase64URL = 'u'
+	// MultibaseBase32 is the multibase prefix of unpadded lowercase RFC 4648 base32 encoded values
+	MultibaseBase32 = 'b'
+	// MultibaseBase32Upper is the multibase prefix of unpadded uppercase RFC 4648 base32 encoded values
+	MultibaseBase32Upper = 'B'
+	// MultibaseBase16 is the multibase prefix of lowercase hex encoded values
+	MultibaseBase16 = 'f'
+	// MultibaseBase16Upper is the multibase prefix of uppercase hex encoded values
+	MultibaseBase16Upper = 'F'
+
+	// multicodec code of JSON encoded data
+	MCjson = 0x0200
+	// multihash code of sha2-256
+	MHsha2_256 = 0x12
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MultibaseEncode encodes data with the encoding of the multibase prefix (e.g. MultibaseBase58BTC)
+func MultibaseEncode(base byte, data []byte) (string, error) {
+	var encoded string
+	switch base {
+	case MultibaseBase58BTC:
+		encoded = base58.Encode(data)
+	case MultibaseBase64URL:
+		encoded = base64.RawURLEncoding.EncodeToString(data)
+	case MultibaseBase32:
+		encoded = strings.ToLower(base32NoPadding.EncodeToString(data))
+	case MultibaseBase32Upper:
+		encoded = base32NoPadding.EncodeToString(data)
+	case MultibaseBase16:
+		encoded = hex.EncodeToString(data)
+	case MultibaseBase16Upper:
+		encoded = strings.ToUpper(hex.EncodeToString(data))
+	default:
+		return "", fmt.Errorf("unsupported multibase encoding: %q", base)
+	}
+	return string(base) + encoded, nil
+}
+
+// MultibaseDecode decodes a multibase string and returns the multibase prefix of the encoding used
+func MultibaseDecode(s string) (byte, []byte, error) {
+	if s == "" {
+		return 0, nil, fmt.Errorf("empty multibase value")
+	}
+	base, encoded := s[0], s[1:]
+	var (
+		data []byte
+		err  error
+	)
+	switch base {
+	case MultibaseBase58BTC:
+		data, err = base58.Decode(encoded)
+	case MultibaseBase64URL:
+		data, err = base64.RawURLEncoding.DecodeString(encoded)
+	case MultibaseBase32, MultibaseBase32Upper:
+		data, err = base32NoPadding.DecodeString(strings.ToUpper(encoded))
+	case MultibaseBase16, MultibaseBase16Upper:
+		data, err = hex.DecodeString(encoded)
+	default:
+		return 0, nil, fmt.Errorf("unsupported multibase encoding: %q", base)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid multibase %q value: %w", base, err)
+	}
+	return base, data, nil
+}
+
+// encodeMultibaseBase58 encodes data as a base58btc multibase string
+func encodeMultibaseBase58(data []byte) string {
+	return string(MultibaseBase58BTC) + base58.Encode(data)
+}
+
+// decodeMultibase decodes a multibase string
+func decodeMultibase(s string) ([]byte, error) {
+	_, data, err := MultibaseDecode(s)
+	return data, err
+}
+
+// multihashSHA256 returns the base58btc multibase encoded sha2-256 multihash of data
+func multihashSHA256(data []byte) string {
+	digest := sha256.Sum256(data)
+	mh := append([]byte{MHsha2_256, byte(len(digest))}, digest[:]...)
+	return encodeMultibaseBase58(mh)
+}
+
+// MulticodecEncode prefixes data with the unsigned varint of the multicodec code
+func MulticodecEncode(code uint64, data []byte) []byte {
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, code)
+	return append(prefix[:n], data...)
+}
+
+// MulticodecDecode splits data into the multicodec code and the remaining bytes
+func MulticodecDecode(data []byte) (uint64, []byte, error) {
+	code, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, nil, fmt.Errorf("invalid multicodec varint prefix")
+	}
+	return code, data[n:], nil
+}
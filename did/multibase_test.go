@@ -0,0 +1,62 @@
+package did
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultibase(t *testing.T) {
+	// test vectors from the multibase specification
+	data := []byte("yes mani !")
+	for base, expected := range map[byte]string{
+		MultibaseBase58BTC:   "z7paNL19xttacUY",
+		MultibaseBase64URL:   "ueWVzIG1hbmkgIQ",
+		MultibaseBase32:      "bpfsxgidnmfxgsibb",
+		MultibaseBase32Upper: "BPFSXGIDNMFXGSIBB",
+		MultibaseBase16:      "f796573206d616e692021",
+		MultibaseBase16Upper: "F796573206D616E692021",
+	} {
+		encoded, err := MultibaseEncode(base, data)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, encoded)
+
+		decodedBase, decoded, err := MultibaseDecode(expected)
+		assert.NoError(t, err)
+		assert.Equal(t, base, decodedBase)
+		assert.Equal(t, data, decoded)
+	}
+
+	_, _, err := MultibaseDecode("m" + "eWVzIG1hbmkgIQ")
+	assert.Error(t, err)
+	_, _, err = MultibaseDecode("z0OIl")
+	assert.Error(t, err)
+}
+
+func TestKeyAgreementMultibase(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ka := doc.KeyAgreement[0]
+	assert.True(t, strings.HasPrefix(ka.PublicKeyMultibase, "z6LS"))
+
+	// any multibase encoding is accepted
+	xk, _ := ka.ECDHPublicKey()
+	hexKey, _ := MultibaseEncode(MultibaseBase16, MulticodecEncode(MCx25519, xk.Bytes()))
+	ka.PublicKeyJwk = nil
+	ka.PublicKeyMultibase = hexKey
+	decoded, err := ka.ECDHPublicKey()
+	assert.NoError(t, err)
+	assert.True(t, xk.Equal(decoded))
+
+	// legacy unprefixed base58 values are still readable
+	ka.PublicKeyMultibase = base58.Encode(xk.Bytes())
+	decoded, err = ka.ECDHPublicKey()
+	assert.NoError(t, err)
+	assert.True(t, xk.Equal(decoded))
+}
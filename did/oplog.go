@@ -0,0 +1,369 @@
+package did
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// did:mailio operation types
+const (
+	OperationCreate     = "create"
+	OperationUpdate     = "update"
+	OperationRotate     = "rotate"
+	OperationDeactivate = "deactivate"
+)
+
+// Operation is an entry of the did:mailio operation log. Every operation is signed by the master key
+// valid before the operation (the create and rotate operations by the master key of their own document) and links to the
+// previous operation by its hash, so the log can't be altered without invalidating all later operations.
+//
+// Create and rotate operations commit to the next master key with NextKeyCommitment. A rotate operation reveals the
+// committed key as the new master key of the document, so a compromised current master key can't rotate the identity away.
+type Operation struct {
+	Type              string    `json:"type"`
+	DID               string    `json:"did"`
+	Previous          string    `json:"previous,omitempty"` // OperationHash of the previous operation, empty for create
+	Created           time.Time `json:"created"`
+	Document          *Document `json:"document,omitempty"`          // the complete new document of create, update and rotate operations
+	NextKeyCommitment string    `json:"nextKeyCommitment,omitempty"` // NextKeyCommitment of the next master key, empty disables rotation
+}
+
+// OperationLog is the append-only log of signed did:mailio operations (compact JWS), oldest first
+type OperationLog []string
+
+// OperationHash returns the hash of the signed operation (base58btc multibase encoded sha2-256 multihash)
+// used as the versionId of the document it produces
+func OperationHash(operation string) string {
+	return multihashSHA256([]byte(operation))
+}
+
+// SignOperation signs the operation with the private key of the master key as a compact JWS
+func SignOperation(op *Operation, privateKey crypto.PrivateKey) (string, error) {
+	alg, err := jwsAlgorithm(privateKey)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return "", err
+	}
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.KeyIDKey, op.DID+MasterKeyFragment); err != nil {
+		return "", err
+	}
+	signed, err := jws.Sign(payload, jws.WithKey(alg, privateKey, jws.WithProtectedHeaders(headers)))
+	if err != nil {
+		return "", err
+	}
+	return string(signed), nil
+}
+
+// NewCreateOperation starts the operation log of the document. The private key must belong to the master key of the document,
+// nextKeyCommitment is the NextKeyCommitment of the master key the document can be rotated to (empty disables rotation).
+func NewCreateOperation(doc *Document, privateKey crypto.PrivateKey, nextKeyCommitment string) (string, error) {
+	return SignOperation(&Operation{
+		Type:              OperationCreate,
+		DID:               doc.ID.String(),
+		Created:           time.Now().UTC(),
+		Document:          doc,
+		NextKeyCommitment: nextKeyCommitment,
+	}, privateKey)
+}
+
+// NewUpdateOperation replaces the document with a new version. The private key must belong to the current master key.
+func NewUpdateOperation(log OperationLog, doc *Document, privateKey crypto.PrivateKey) (string, error) {
+	if len(log) == 0 {
+		return "", fmt.Errorf("%w: update requires an existing operation log", ErrInvalidOperation)
+	}
+	return SignOperation(&Operation{
+		Type:     OperationUpdate,
+		DID:      doc.ID.String(),
+		Previous: OperationHash(log[len(log)-1]),
+		Created:  time.Now().UTC(),
+		Document: doc,
+	}, privateKey)
+}
+
+// NewRotateOperation replaces the master key with the key committed to by the last create or rotate operation.
+// The document's master key must be the committed key and the private key must belong to it.
+// nextKeyCommitment commits to the master key of the following rotation.
+func NewRotateOperation(log OperationLog, doc *Document, privateKey crypto.PrivateKey, nextKeyCommitment string) (string, error) {
+	if len(log) == 0 {
+		return "", fmt.Errorf("%w: rotation requires an existing operation log", ErrInvalidOperation)
+	}
+	return SignOperation(&Operation{
+		Type:              OperationRotate,
+		DID:               doc.ID.String(),
+		Previous:          OperationHash(log[len(log)-1]),
+		Created:           time.Now().UTC(),
+		Document:          doc,
+		NextKeyCommitment: nextKeyCommitment,
+	}, privateKey)
+}
+
+// NewDeactivateOperation permanently deactivates the DID. The private key must belong to the current master key.
+func NewDeactivateOperation(log OperationLog, did DID, privateKey crypto.PrivateKey) (string, error) {
+	if len(log) == 0 {
+		return "", fmt.Errorf("%w: deactivation requires an existing operation log", ErrInvalidOperation)
+	}
+	return SignOperation(&Operation{
+		Type:     OperationDeactivate,
+		DID:      "did:" + did.Protocol() + ":" + did.Value(),
+		Previous: OperationHash(log[len(log)-1]),
+		Created:  time.Now().UTC(),
+	}, privateKey)
+}
+
+// NewTombstoneDocument returns the document of a deactivated DID: the DID without any keys or services
+func NewTombstoneDocument(did DID) *Document {
+	return &Document{
+		Context: []string{CtxDIDv1},
+		ID:      DID{raw: "did:" + did.Protocol() + ":" + did.Value(), proto: did.Protocol(), value: did.Value()},
+	}
+}
+
+// ParseOperation decodes the signed operation without verifying it
+func ParseOperation(operation string) (*Operation, error) {
+	msg, err := jws.Parse([]byte(operation))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	var op Operation
+	if err := json.Unmarshal(msg.Payload(), &op); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	return &op, nil
+}
+
+// Replay verifies every operation of the log and returns the resulting document. DocumentMetadata.VersionID
+// is the OperationHash of the last operation, Created and Updated the times of the first and the last operation.
+// A deactivated DID replays to the NewTombstoneDocument with DocumentMetadata.Deactivated set.
+func (l OperationLog) Replay(did DID) (*Document, *DocumentMetadata, error) {
+	if len(l) == 0 {
+		return nil, nil, fmt.Errorf("%w: empty operation log", ErrInvalidOperation)
+	}
+	expected := "did:" + did.Protocol() + ":" + did.Value()
+
+	var (
+		doc        *Document
+		commitment string
+		md         = &DocumentMetadata{}
+	)
+	for i, signed := range l {
+		op, err := ParseOperation(signed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if md.Deactivated {
+			return nil, nil, fmt.Errorf("%w: operation %d after deactivation", ErrInvalidOperation, i)
+		}
+		if op.DID != expected {
+			return nil, nil, fmt.Errorf("%w: operation %d is for %q", ErrInvalidOperation, i, op.DID)
+		}
+		if i == 0 {
+			if op.Type != OperationCreate || op.Previous != "" {
+				return nil, nil, fmt.Errorf("%w: log must start with a create operation", ErrInvalidOperation)
+			}
+		} else {
+			if op.Type == OperationCreate {
+				return nil, nil, fmt.Errorf("%w: operation %d: duplicate create", ErrInvalidOperation, i)
+			}
+			if op.Previous != OperationHash(l[i-1]) {
+				return nil, nil, fmt.Errorf("%w: operation %d doesn't link to the previous operation", ErrInvalidOperation, i)
+			}
+			if op.Created.Before(*md.Updated) {
+				return nil, nil, fmt.Errorf("%w: operation %d predates the previous operation", ErrInvalidOperation, i)
+			}
+		}
+
+		// the signing key is the master key valid before the operation, create and rotate are self-signed
+		signer := doc
+		if op.Type == OperationCreate || op.Type == OperationRotate {
+			signer = op.Document
+		}
+		if signer == nil {
+			return nil, nil, fmt.Errorf("%w: operation %d without document", ErrInvalidOperation, i)
+		}
+		if err := verifyOperationSignature(signed, signer); err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+
+		switch op.Type {
+		case OperationCreate, OperationUpdate, OperationRotate:
+			if op.Document == nil {
+				return nil, nil, fmt.Errorf("%w: operation %d without document", ErrInvalidOperation, i)
+			}
+			if err := verifyOperationMasterKey(op, did, doc, commitment); err != nil {
+				return nil, nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidOperation, i, err)
+			}
+			if err := op.Document.Validate(); err != nil {
+				return nil, nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidOperation, i, err)
+			}
+			doc = op.Document
+			if op.Type != OperationUpdate {
+				commitment = op.NextKeyCommitment
+			} else if op.NextKeyCommitment != "" {
+				return nil, nil, fmt.Errorf("%w: operation %d: only create and rotate commit to the next master key", ErrInvalidOperation, i)
+			}
+		case OperationDeactivate:
+			doc = NewTombstoneDocument(did)
+			md.Deactivated = true
+		default:
+			return nil, nil, fmt.Errorf("%w: operation %d: unknown type %q", ErrInvalidOperation, i, op.Type)
+		}
+
+		created := op.Created
+		if i == 0 {
+			md.Created = &created
+		}
+		md.Updated = &created
+		md.VersionID = OperationHash(signed)
+	}
+	return doc, md, nil
+}
+
+// ReplayVersion replays the log up to the selected version: the operation with the OperationHash VersionID or the last
+// operation created at or before VersionTime. NextVersionID and NextUpdate of the returned metadata refer to the
+// following operation, if any. Fails with ErrNotFound when the log has no such version.
+func (l OperationLog) ReplayVersion(did DID, version VersionSelector) (*Document, *DocumentMetadata, error) {
+	n := -1
+	for i, signed := range l {
+		if version.VersionID != "" {
+			if OperationHash(signed) == version.VersionID {
+				n = i
+				break
+			}
+			continue
+		}
+		op, err := ParseOperation(signed)
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		if op.Created.After(version.VersionTime) {
+			break
+		}
+		n = i
+	}
+	if n < 0 {
+		return nil, nil, newResolutionError(ErrCodeNotFound, "no version of %s matches %s", did.String(), version)
+	}
+	if version.VersionID != "" && !version.VersionTime.IsZero() {
+		op, err := ParseOperation(l[n])
+		if err != nil {
+			return nil, nil, err
+		}
+		if op.Created.After(version.VersionTime) {
+			return nil, nil, newResolutionError(ErrCodeNotFound, "version %s of %s was created after %s", version.VersionID, did.String(), version.VersionTime.Format(time.RFC3339))
+		}
+	}
+
+	doc, md, err := l[:n+1].Replay(did)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n+1 < len(l) {
+		next, err := ParseOperation(l[n+1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("operation %d: %w", n+1, err)
+		}
+		nextUpdate := next.Created
+		md.NextVersionID = OperationHash(l[n+1])
+		md.NextUpdate = &nextUpdate
+	}
+	return doc, md, nil
+}
+
+// verifyOperationMasterKey checks the master key of the operation's document: the create document's master key must hash
+// to the Mailio address, update documents must keep the current master key and rotate documents must reveal the
+// committed next master key
+func verifyOperationMasterKey(op *Operation, did DID, current *Document, commitment string) error {
+	if op.Type == OperationCreate {
+		return VerifyMailioDocument(op.Document, did)
+	}
+	expected := "did:" + did.Protocol() + ":" + did.Value()
+	if op.Document.ID.String() != expected {
+		return fmt.Errorf("%w: document id %q does not match %q", ErrInvalidDocument, op.Document.ID.String(), expected)
+	}
+	masterKey, err := documentMasterKey(op.Document)
+	if err != nil {
+		return err
+	}
+	if op.Type == OperationRotate {
+		if commitment == "" {
+			return fmt.Errorf("no next master key committed to")
+		}
+		revealed, err := NextKeyCommitment(masterKey)
+		if err != nil {
+			return err
+		}
+		if revealed != commitment {
+			return fmt.Errorf("master key doesn't match the next key commitment")
+		}
+		return nil
+	}
+	currentKey, err := documentMasterKey(current)
+	if err != nil {
+		return err
+	}
+	if !publicKeyEqual(masterKey, currentKey) {
+		return fmt.Errorf("master key can only be changed by a rotate operation")
+	}
+	return nil
+}
+
+// publicKeyEqual compares public keys by their multicodec encoding
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	codeA, rawA, errA := multicodecPublicKey(a)
+	codeB, rawB, errB := multicodecPublicKey(b)
+	return errA == nil && errB == nil && codeA == codeB && bytes.Equal(rawA, rawB)
+}
+
+// verifyOperationSignature verifies the operation JWS with the master key of the document
+func verifyOperationSignature(operation string, doc *Document) error {
+	publicKey, err := documentMasterKey(doc)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	alg, err := jwsAlgorithm(publicKey)
+	if err != nil {
+		return err
+	}
+	if _, err := jws.Verify([]byte(operation), jws.WithKey(alg, publicKey)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// jwsAlgorithm returns the JWS signature algorithm of a public or private key.
+// secp256k1 (ES256K) is refused explicitly, jwx only supports it when built with the jwx_es256k tag.
+func jwsAlgorithm(key interface{}) (jwa.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case *secp256k1.PublicKey, *secp256k1.PrivateKey:
+		return "", fmt.Errorf("%w: secp256k1 (ES256K) master keys can't sign operation logs", ErrUnsupportedKeyType)
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return jwa.EdDSA, nil
+	case *ecdsa.PrivateKey:
+		return jwsAlgorithm(&k.PublicKey)
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return jwa.ES256, nil
+		case elliptic.P384():
+			return jwa.ES384, nil
+		}
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return jwa.RS256, nil
+	}
+	return "", fmt.Errorf("%w: no signature algorithm for %T", ErrUnsupportedKeyType, key)
+}
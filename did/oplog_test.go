@@ -0,0 +1,405 @@
+package did
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/stretchr/testify/assert"
+)
+
+// generateSigningMailioKey generates mailio keys keeping the private master key
+func generateSigningMailioKey(t *testing.T) (*MailioKey, ed25519.PrivateKey) {
+	mk, err := GenerateMailioPublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	mk.MasterSignKey.PublicKey = pub
+	return mk, priv
+}
+
+func TestOperationLog(t *testing.T) {
+	fileStore, err := NewFileDocumentStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, store := range []DocumentStore{NewMemoryDocumentStore(), fileStore} {
+		ctx := context.Background()
+		mk, priv := generateSigningMailioKey(t)
+		mkMailio, _ := GenerateMailioPublicKeys()
+		did, _ := mk.DIDFromKey()
+
+		doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+		create, err := NewCreateOperation(doc, priv, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, md, err := PublishOperation(ctx, store, did, create)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, OperationHash(create), md.VersionID)
+
+		doc.AlsoKnownAs = []string{"https://mail.io/alice"}
+		update, err := NewUpdateOperation(OperationLog{create}, doc, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := PublishOperation(ctx, store, did, update); err != nil {
+			t.Fatal(err)
+		}
+
+		resolved, _, md, err := NewMailioResolver(store).Resolve(ctx, did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, []string{"https://mail.io/alice"}, resolved.AlsoKnownAs)
+		assert.Equal(t, OperationHash(update), md.VersionID)
+		assert.NotNil(t, md.Created)
+		assert.False(t, md.Updated.Before(*md.Created))
+
+		// documents are also readable without replaying the log
+		stored, err := store.Get(ctx, did.Value())
+		assert.NoError(t, err)
+		assert.Equal(t, resolved.AlsoKnownAs, stored.AlsoKnownAs)
+
+		// an update signed by a foreign key is rejected and not stored
+		_, otherPriv := generateSigningMailioKey(t)
+		forged, _ := NewUpdateOperation(OperationLog{create, update}, doc, otherPriv)
+		_, _, err = PublishOperation(ctx, store, did, forged)
+		assert.ErrorIs(t, err, ErrInvalidSignature)
+		log, _ := store.(OperationLogStore).GetOperations(ctx, did.Value())
+		assert.Len(t, log, 2)
+	}
+}
+
+func TestOperationLogTampering(t *testing.T) {
+	mk, priv := generateSigningMailioKey(t)
+	mkMailio, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	create, _ := NewCreateOperation(doc, priv, "")
+	update, _ := NewUpdateOperation(OperationLog{create}, doc, priv)
+	second, _ := NewUpdateOperation(OperationLog{create, update}, doc, priv)
+
+	_, _, err := OperationLog{create, update, second}.Replay(did)
+	assert.NoError(t, err)
+
+	// dropping an operation breaks the hash chain
+	_, _, err = OperationLog{create, second}.Replay(did)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	// modified payload fails signature verification
+	parts := strings.Split(update, ".")
+	op, _ := ParseOperation(update)
+	op.Document.AlsoKnownAs = []string{"https://evil.example"}
+	tampered, _ := SignOperation(op, priv)
+	parts[1] = strings.Split(tampered, ".")[1]
+	_, _, err = OperationLog{create, strings.Join(parts, ".")}.Replay(did)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// nothing follows a deactivation
+	deactivate, _ := NewDeactivateOperation(OperationLog{create}, did, priv)
+	_, md, err := OperationLog{create, deactivate}.Replay(did)
+	assert.NoError(t, err)
+	assert.True(t, md.Deactivated)
+	after, _ := NewUpdateOperation(OperationLog{create, deactivate}, doc, priv)
+	_, _, err = OperationLog{create, deactivate, after}.Replay(did)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	// the log of another DID
+	otherMk, _ := GenerateMailioPublicKeys()
+	otherDID, _ := otherMk.DIDFromKey()
+	_, _, err = OperationLog{create}.Replay(otherDID)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	// secp256k1 master keys can't sign operations
+	secpPriv, _ := secp256k1.GeneratePrivateKey()
+	_, err = NewCreateOperation(doc, secpPriv, "")
+	assert.ErrorIs(t, err, ErrUnsupportedKeyType)
+}
+
+func TestOperationLogRotation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDocumentStore()
+	mk, priv := generateSigningMailioKey(t)
+	mkMailio, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+
+	nextPub, nextPriv, _ := ed25519.GenerateKey(rand.Reader)
+	commitment, err := NextKeyCommitment(nextPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	create, _ := NewCreateOperation(doc, priv, commitment)
+	if _, _, err := PublishOperation(ctx, store, did, create); err != nil {
+		t.Fatal(err)
+	}
+
+	// an update can't replace the master key
+	_, otherPriv := generateSigningMailioKey(t)
+	otherPub := otherPriv.Public().(ed25519.PublicKey)
+	stolen := &MailioKey{InceptionKey: mk.MasterSignKey, MasterSignKey: &Key{PublicKey: otherPub}, MasterAgreementKey: mk.MasterAgreementKey}
+	stolenDoc, _ := NewMailioDIDDocument(stolen, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	update, _ := NewUpdateOperation(OperationLog{create}, stolenDoc, priv)
+	_, _, err = PublishOperation(ctx, store, did, update)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	// nor can a rotation to an uncommitted key
+	rotate, _ := NewRotateOperation(OperationLog{create}, stolenDoc, otherPriv, "")
+	_, _, err = PublishOperation(ctx, store, did, rotate)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+
+	// rotation to the committed key keeps the DID
+	rotated := &MailioKey{InceptionKey: mk.MasterSignKey, MasterSignKey: &Key{PublicKey: nextPub}, MasterAgreementKey: mk.MasterAgreementKey}
+	rotatedDID, _ := rotated.DIDFromKey()
+	assert.Equal(t, did.String(), rotatedDID.String())
+	rotatedDoc, _ := NewMailioDIDDocument(rotated, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	rotate, _ = NewRotateOperation(OperationLog{create}, rotatedDoc, nextPriv, "")
+	if _, _, err := PublishOperation(ctx, store, did, rotate); err != nil {
+		t.Fatal(err)
+	}
+	assert.ErrorIs(t, VerifyMailioDocument(rotatedDoc, did), ErrInvalidDocument)
+
+	resolved, _, md, err := NewMailioResolver(store).Resolve(ctx, did)
+	assert.NoError(t, err)
+	masterKey, _ := documentMasterKey(resolved)
+	assert.Equal(t, nextPub, masterKey)
+	assert.Equal(t, OperationHash(rotate), md.VersionID)
+
+	// the old master key lost control, the new one updates
+	log := OperationLog{create, rotate}
+	update, _ = NewUpdateOperation(log, rotatedDoc, priv)
+	_, _, err = PublishOperation(ctx, store, did, update)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	update, _ = NewUpdateOperation(log, rotatedDoc, nextPriv)
+	_, _, err = PublishOperation(ctx, store, did, update)
+	assert.NoError(t, err)
+
+	// the commitment was used up
+	again, _ := NewRotateOperation(append(log, update), rotatedDoc, nextPriv, "")
+	_, _, err = PublishOperation(ctx, store, did, again)
+	assert.ErrorIs(t, err, ErrInvalidOperation)
+}
+
+func TestOperationLogDeactivation(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDocumentStore()
+	resolver := NewMailioResolver(store)
+	mk, priv := generateSigningMailioKey(t)
+	mkMailio, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+
+	vcPub, vcPriv, _ := ed25519.GenerateKey(rand.Reader)
+	mk.VerificationKeys = []*Key{{PublicKey: vcPub}}
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	create, _ := NewCreateOperation(doc, priv, "")
+	if _, _, err := PublishOperation(ctx, store, did, create); err != nil {
+		t.Fatal(err)
+	}
+
+	vc := NewVerifiableCredential(did.String())
+	if err := vc.CreateProofWithMethod(vcPriv, did.String()+"#1"); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := vc.VerifyProofWithResolver(ctx, resolver)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	_, err = AuthorizeProof(ctx, resolver, doc, &Proof{VerificationMethod: did.String() + MasterKeyFragment, ProofPurpose: RelationshipAuthentication})
+	assert.NoError(t, err)
+
+	// only the master key deactivates
+	_, otherPriv := generateSigningMailioKey(t)
+	forged, _ := NewDeactivateOperation(OperationLog{create}, did, otherPriv)
+	_, _, err = PublishOperation(ctx, store, did, forged)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	deactivate, _ := NewDeactivateOperation(OperationLog{create}, did, priv)
+	if _, _, err := PublishOperation(ctx, store, did, deactivate); err != nil {
+		t.Fatal(err)
+	}
+	masterProof := &Proof{VerificationMethod: did.String() + MasterKeyFragment, ProofPurpose: RelationshipAuthentication}
+
+	resolved, _, md, err := resolver.Resolve(ctx, did)
+	assert.NoError(t, err)
+	assert.True(t, md.Deactivated)
+	assert.Equal(t, NewTombstoneDocument(did), resolved)
+	stored, _ := store.Get(ctx, did.Value())
+	assert.Empty(t, stored.VerificationMethod)
+	assert.Empty(t, stored.Service)
+
+	_, err = vc.VerifyProofWithResolver(ctx, resolver)
+	assert.ErrorIs(t, err, ErrDeactivated)
+	_, err = AuthorizeProof(ctx, resolver, resolved, masterProof)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	// a copy of the document from before the deactivation doesn't authorize the master key either
+	_, err = AuthorizeProof(ctx, resolver, doc, masterProof)
+	assert.ErrorIs(t, err, ErrDeactivated)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	// a document controlled by the deactivated DID
+	controlledMk, _ := GenerateMailioPublicKeys()
+	controlledDID, _ := controlledMk.DIDFromKey()
+	controlled, _ := NewDocumentBuilder(controlledDID).
+		AddController(did.String()).
+		AddKey(controlledDID.String()+MasterKeyFragment, controlledMk.MasterSignKey, RelationshipAuthentication).
+		Build()
+	_, err = AuthorizeProof(ctx, resolver, controlled, masterProof)
+	assert.ErrorIs(t, err, ErrDeactivated)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestOperationLogVersions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDocumentStore()
+	resolver := NewMailioResolver(store)
+	mk, priv := generateSigningMailioKey(t)
+	mkMailio, _ := GenerateMailioPublicKeys()
+	did, _ := mk.DIDFromKey()
+
+	vcPub, vcPriv, _ := ed25519.GenerateKey(rand.Reader)
+	mk.VerificationKeys = []*Key{{PublicKey: vcPub}}
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	create, _ := NewCreateOperation(doc, priv, "")
+	if _, _, err := PublishOperation(ctx, store, did, create); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	vc := NewVerifiableCredential(did.String())
+	if err := vc.CreateProofWithMethod(vcPriv, did.String()+"#1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	// replace the credential key
+	newPub, newPriv, _ := ed25519.GenerateKey(rand.Reader)
+	mk.VerificationKeys = []*Key{{PublicKey: newPub}}
+	updated, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	update, _ := NewUpdateOperation(OperationLog{create}, updated, priv)
+	if _, _, err := PublishOperation(ctx, store, did, update); err != nil {
+		t.Fatal(err)
+	}
+
+	old, _, md, err := ResolveVersion(ctx, resolver, did, VersionSelector{VersionID: OperationHash(create)})
+	assert.NoError(t, err)
+	assert.Equal(t, OperationHash(create), md.VersionID)
+	assert.Equal(t, OperationHash(update), md.NextVersionID)
+	assert.NotNil(t, md.NextUpdate)
+	oldKey, _ := old.FindVerificationMethod("#1")
+	pk, _ := oldKey.GetPublicKey()
+	assert.Equal(t, []byte(vcPub), (*pk).([]byte))
+
+	_, _, md, err = ResolveVersion(ctx, resolver, did, VersionSelector{VersionTime: time.Now()})
+	assert.NoError(t, err)
+	assert.Equal(t, OperationHash(update), md.VersionID)
+	assert.Empty(t, md.NextVersionID)
+	_, _, _, err = ResolveVersion(ctx, resolver, did, VersionSelector{VersionTime: md.Created.Add(-time.Hour)})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, _, err = ResolveVersion(ctx, resolver, did, VersionSelector{VersionID: "zQmUnknown"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	res, err := Dereference(ctx, resolver, did.String()+"?versionId="+OperationHash(create)+"#1")
+	assert.NoError(t, err)
+	assert.Equal(t, oldKey.PublicKeyJwk, res.VerificationMethod.PublicKeyJwk)
+
+	// credentials signed with a rotated key are refused
+	_, err = vc.VerifyProofWithIssuer(updated)
+	assert.Error(t, err)
+	_, err = vc.VerifyProofWithResolver(ctx, NewCachingResolver(resolver, time.Minute, 0))
+	assert.Error(t, err)
+
+	// credentials signed with the current key are verified
+	current := NewVerifiableCredential(did.String())
+	assert.NoError(t, current.CreateProofWithMethod(newPriv, did.String()+"#1"))
+	ok, err := current.VerifyProofWithResolver(ctx, resolver)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the creation time is signed, so the proof can't be moved into the version before the rotation
+	current.Proof.Created = vc.Proof.Created
+	_, err = current.VerifyProofWithResolver(ctx, resolver)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	// and a proof signed as created before the rotation isn't valid for the historic version
+	backdated := NewVerifiableCredential(did.String())
+	assert.NoError(t, backdated.CreateProofWithMethod(newPriv, did.String()+"#1"))
+	backdated.Proof.Created, backdated.Proof.Jws = vc.Proof.Created, ""
+	payload, _ := credentialEncMode.Marshal(backdated)
+	signature, _ := jws.Sign(payload, jws.WithKey(jwa.EdDSA, newPriv))
+	backdated.Proof.Jws = string(signature)
+	_, err = backdated.VerifyProofWithResolver(ctx, resolver)
+	assert.Error(t, err)
+
+	// resolvers without history only serve the current version
+	keyDID, _ := DIDKeyFromPublicKey(vcPub)
+	_, _, _, err = ResolveVersion(ctx, NewKeyResolver(), keyDID, VersionSelector{VersionTime: time.Now()})
+	assert.NoError(t, err)
+	_, _, _, err = ResolveVersion(ctx, NewKeyResolver(), keyDID, VersionSelector{VersionID: "1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPublishOperationConcurrent(t *testing.T) {
+	fileStore, err := NewFileDocumentStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, store := range []DocumentStore{NewMemoryDocumentStore(), fileStore} {
+		ctx := context.Background()
+		mk, priv := generateSigningMailioKey(t)
+		mkMailio, _ := GenerateMailioPublicKeys()
+		did, _ := mk.DIDFromKey()
+		doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+		create, _ := NewCreateOperation(doc, priv, "")
+		if _, _, err := PublishOperation(ctx, store, did, create); err != nil {
+			t.Fatal(err)
+		}
+
+		// all updates link to the create operation, only one of them may be appended
+		const publishers = 8
+		var (
+			wg        sync.WaitGroup
+			mu        sync.Mutex
+			published int
+		)
+		for i := 0; i < publishers; i++ {
+			updated := *doc
+			updated.AlsoKnownAs = []string{"https://mail.io/" + strconv.Itoa(i)}
+			update, _ := NewUpdateOperation(OperationLog{create}, &updated, priv)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _, err := PublishOperation(ctx, store, did, update)
+				if err == nil {
+					mu.Lock()
+					published++
+					mu.Unlock()
+					return
+				}
+				assert.ErrorIs(t, err, ErrConflict)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, published)
+
+		resolved, _, md, err := NewMailioResolver(store).Resolve(ctx, did)
+		assert.NoError(t, err)
+		log, _ := store.(OperationLogStore).GetOperations(ctx, did.Value())
+		assert.Len(t, log, 2)
+		assert.Equal(t, OperationHash(log[1]), md.VersionID)
+		// the stored document is the one of the appended update
+		stored, err := store.Get(ctx, did.Value())
+		assert.NoError(t, err)
+		assert.Equal(t, resolved.AlsoKnownAs, stored.AlsoKnownAs)
+	}
+}
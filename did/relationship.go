@@ -0,0 +1,155 @@
+package did
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// verification relationships (https://www.w3.org/TR/did-core/#verification-relationships)
+// the names double as the proofPurpose of proofs created with the related verification methods
+const (
+	RelationshipAuthentication       = "authentication"
+	RelationshipAssertionMethod      = "assertionMethod"
+	RelationshipCapabilityInvocation = "capabilityInvocation"
+	RelationshipCapabilityDelegation = "capabilityDelegation"
+)
+
+// VerificationRelationship is an entry of a verification relationship (authentication, assertionMethod, ...).
+// It's either a reference (DID URL) to one of the document verification methods or an embedded verification method
+// (https://www.w3.org/TR/did-core/#referring-to-verification-methods).
+type VerificationRelationship struct {
+	Reference string
+	Method    *VerificationMethod
+}
+
+// NewReferenceRelationship creates a relationship referring to the verification method id (absolute or relative "#fragment")
+func NewReferenceRelationship(id string) VerificationRelationship {
+	return VerificationRelationship{
+		Reference: id,
+	}
+}
+
+// NewEmbeddedRelationship creates a relationship embedding the verification method
+func NewEmbeddedRelationship(vm VerificationMethod) VerificationRelationship {
+	return VerificationRelationship{
+		Method: &vm,
+	}
+}
+
+// IsReference returns true if the relationship refers to a verification method rather than embedding it
+func (r VerificationRelationship) IsReference() bool {
+	return r.Method == nil
+}
+
+// ID returns the referenced id or the id of the embedded verification method
+func (r VerificationRelationship) ID() string {
+	if r.Method != nil {
+		return r.Method.ID
+	}
+	return r.Reference
+}
+
+func (r VerificationRelationship) MarshalJSON() ([]byte, error) {
+	if r.Method != nil {
+		return json.Marshal(r.Method)
+	}
+	return json.Marshal(r.Reference)
+}
+
+func (r *VerificationRelationship) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var ref string
+		if err := json.Unmarshal(b, &ref); err != nil {
+			return err
+		}
+		*r = NewReferenceRelationship(ref)
+		return nil
+	}
+	var vm VerificationMethod
+	if err := json.Unmarshal(b, &vm); err != nil {
+		return fmt.Errorf("verification relationship must be a reference or an embedded verification method: %w", err)
+	}
+	*r = NewEmbeddedRelationship(vm)
+	return nil
+}
+
+// ResolveRelationship returns the verification method of the relationship, looking up references in VerificationMethod
+func (d *Document) ResolveRelationship(r VerificationRelationship) (*VerificationMethod, error) {
+	if r.Method != nil {
+		return r.Method, nil
+	}
+	return d.FindVerificationMethod(r.Reference)
+}
+
+// resolveRelationships resolves all the relationships. Dangling references fail with ErrKeyNotFound.
+func (d *Document) resolveRelationships(relationships []VerificationRelationship) ([]*VerificationMethod, error) {
+	methods := make([]*VerificationMethod, 0, len(relationships))
+	for _, r := range relationships {
+		vm, err := d.ResolveRelationship(r)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, vm)
+	}
+	return methods, nil
+}
+
+// findRelationship finds the verification method by id within the relationships
+func (d *Document) findRelationship(relationships []VerificationRelationship, id string) (*VerificationMethod, error) {
+	for _, r := range relationships {
+		if d.sameID(id, r.ID()) {
+			return d.ResolveRelationship(r)
+		}
+	}
+	return nil, fmt.Errorf("no verification relationship found by ID %q: %w", id, ErrKeyNotFound)
+}
+
+// findEmbeddedMethod finds the verification method by id among the methods embedded in the verification relationships
+func (d *Document) findEmbeddedMethod(id string) (*VerificationMethod, error) {
+	for _, relationship := range [][]VerificationRelationship{d.Authentication, d.AssertionMethod, d.CapabilityInvocation, d.CapabilityDelegation} {
+		for _, r := range relationship {
+			if r.Method != nil && d.sameID(id, r.Method.ID) {
+				return r.Method, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no embedded verification method found by ID %q: %w", id, ErrKeyNotFound)
+}
+
+// Relationship returns the entries of the verification relationship by its name (e.g. RelationshipAssertionMethod)
+// or nil if the name is unknown
+func (d *Document) Relationship(name string) []VerificationRelationship {
+	switch name {
+	case RelationshipAuthentication:
+		return d.Authentication
+	case RelationshipAssertionMethod:
+		return d.AssertionMethod
+	case RelationshipCapabilityInvocation:
+		return d.CapabilityInvocation
+	case RelationshipCapabilityDelegation:
+		return d.CapabilityDelegation
+	}
+	return nil
+}
+
+// FindRelationshipMethod finds the verification method by id only if it's authorized for the relationship
+func (d *Document) FindRelationshipMethod(relationship string, id string) (*VerificationMethod, error) {
+	return d.findRelationship(d.Relationship(relationship), id)
+}
+
+// AuthenticationMethods returns all authentication verification methods with references resolved
+func (d *Document) AuthenticationMethods() ([]*VerificationMethod, error) {
+	return d.resolveRelationships(d.Authentication)
+}
+
+// FindAuthenticationMethod finds the verification method by id only if it's authorized for authentication
+func (d *Document) FindAuthenticationMethod(id string) (*VerificationMethod, error) {
+	return d.findRelationship(d.Authentication, id)
+}
+
+// FindAssertionMethod finds the verification method by id only if it's authorized for assertions (e.g. issuing credentials)
+func (d *Document) FindAssertionMethod(id string) (*VerificationMethod, error) {
+	return d.findRelationship(d.AssertionMethod, id)
+}
@@ -0,0 +1,58 @@
+package did
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationRelationshipRoundTrip(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	authKey, _ := GenerateMailioPublicKeys()
+	mk.AuthenticationKeys = []*Key{authKey.MasterSignKey}
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed Document
+	if err := json.Unmarshal(b, &parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, parsed.Authentication, 2)
+	assert.True(t, parsed.Authentication[0].IsReference())
+	assert.Equal(t, mailioDID(t, mk)+MasterKeyFragment, parsed.Authentication[0].ID())
+	assert.False(t, parsed.Authentication[1].IsReference())
+	assert.Equal(t, mailioDID(t, mk)+"#auth-1", parsed.Authentication[1].ID())
+
+	methods, err := parsed.AuthenticationMethods()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range []crypto.PublicKey{mk.MasterSignKey.PublicKey, authKey.MasterSignKey.PublicKey} {
+		pk, pkErr := methods[i].GetPublicKey()
+		if pkErr != nil {
+			t.Fatal(pkErr)
+		}
+		assert.True(t, bytes.Equal(key.(ed25519.PublicKey), (*pk).([]byte)))
+	}
+
+	vm, err := parsed.FindAuthenticationMethod("#auth-1")
+	assert.NoError(t, err)
+	assert.Equal(t, mailioDID(t, mk)+"#auth-1", vm.ID)
+
+	// master key is an authentication method, other verification methods are not
+	parsed.VerificationMethod = append(parsed.VerificationMethod, VerificationMethod{ID: "#1"})
+	_, err = parsed.FindAuthenticationMethod("#1")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	var invalid VerificationRelationship
+	assert.Error(t, json.Unmarshal([]byte(`42`), &invalid))
+}
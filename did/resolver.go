@@ -0,0 +1,168 @@
+package did
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// ContentTypeDIDJSON is the media type of a plain JSON DID document representation
+	ContentTypeDIDJSON = "application/did+json"
+	// ContentTypeDIDLDJSON is the media type of a JSON-LD DID document representation
+	ContentTypeDIDLDJSON = "application/did+ld+json"
+)
+
+// Resolver turns a DID into its DID document. Every DID method (did:mailio, did:web, did:key, ...)
+// provides its own Resolver which can be plugged into a ResolverRegistry.
+type Resolver interface {
+	Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error)
+}
+
+// ResolverFunc is an adapter allowing the use of ordinary functions as a Resolver
+type ResolverFunc func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error)
+
+// Resolve calls f(ctx, did)
+func (f ResolverFunc) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	return f(ctx, did)
+}
+
+// VersionSelector selects a historical version of a DID document, as the versionId and versionTime DID parameters do.
+// The zero value selects the current version.
+type VersionSelector struct {
+	VersionID   string
+	VersionTime time.Time // the version valid at the time, zero when not set
+}
+
+// IsZero reports whether the selector selects the current version
+func (v VersionSelector) IsZero() bool {
+	return v.VersionID == "" && v.VersionTime.IsZero()
+}
+
+// String returns the selector as DID parameters (e.g. versionId=z...)
+func (v VersionSelector) String() string {
+	params := url.Values{}
+	if v.VersionID != "" {
+		params.Set(DIDParamVersionID, v.VersionID)
+	}
+	if !v.VersionTime.IsZero() {
+		params.Set(DIDParamVersionTime, v.VersionTime.UTC().Format(time.RFC3339))
+	}
+	return params.Encode()
+}
+
+// VersionResolver is implemented by resolvers able to resolve historical versions of DID documents.
+// The DocumentMetadata of a historical version has NextVersionID and NextUpdate set when a later version exists.
+type VersionResolver interface {
+	ResolveVersion(ctx context.Context, did DID, version VersionSelector) (*Document, *ResolutionMetadata, *DocumentMetadata, error)
+}
+
+// ResolveVersion resolves the selected version of the DID document. Resolvers not implementing VersionResolver
+// only know the current version, which is returned when it matches the selector, otherwise resolution fails with notFound.
+func ResolveVersion(ctx context.Context, resolver Resolver, did DID, version VersionSelector) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	if vr, ok := resolver.(VersionResolver); ok {
+		return vr.ResolveVersion(ctx, did, version)
+	}
+	doc, resMeta, docMeta, err := resolver.Resolve(ctx, did)
+	if err != nil || version.IsZero() {
+		return doc, resMeta, docMeta, err
+	}
+	if docMeta == nil {
+		docMeta = &DocumentMetadata{}
+	}
+	if version.VersionID != "" && version.VersionID != docMeta.VersionID {
+		return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, newResolutionError(ErrCodeNotFound, "no version of %s matches %s", did.String(), version)
+	}
+	if !version.VersionTime.IsZero() {
+		since := docMeta.Updated
+		if since == nil {
+			since = docMeta.Created
+		}
+		if since != nil && since.After(version.VersionTime) {
+			return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, newResolutionError(ErrCodeNotFound, "no version of %s matches %s", did.String(), version)
+		}
+	}
+	return doc, resMeta, docMeta, nil
+}
+
+// ResolutionMetadata contains information about the resolution process itself.
+// Error holds one of the ErrCode* values when the resolution failed.
+// Expires is set by resolvers that know how long the result may be cached (e.g. from HTTP cache headers).
+type ResolutionMetadata struct {
+	ContentType string     `json:"contentType,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+// DocumentMetadata contains information about the resolved DID document (https://www.w3.org/TR/did-core/#did-document-metadata)
+type DocumentMetadata struct {
+	Created       *time.Time `json:"created,omitempty"`
+	Updated       *time.Time `json:"updated,omitempty"`
+	Deactivated   bool       `json:"deactivated,omitempty"`
+	NextUpdate    *time.Time `json:"nextUpdate,omitempty"`
+	VersionID     string     `json:"versionId,omitempty"`
+	NextVersionID string     `json:"nextVersionId,omitempty"`
+	EquivalentID  []string   `json:"equivalentId,omitempty"`
+	CanonicalID   string     `json:"canonicalId,omitempty"`
+}
+
+// ResolverRegistry dispatches resolution to the Resolver registered for the DID method (e.g. "mailio" for did:mailio).
+// ResolverRegistry is itself a Resolver and is safe for concurrent use.
+type ResolverRegistry struct {
+	mu      sync.RWMutex
+	methods map[string]Resolver
+}
+
+// NewResolverRegistry creates an empty registry
+func NewResolverRegistry() *ResolverRegistry {
+	return &ResolverRegistry{
+		methods: make(map[string]Resolver),
+	}
+}
+
+// Register registers a resolver for the DID method. Registering the same method twice replaces the previous resolver.
+func (r *ResolverRegistry) Register(method string, resolver Resolver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[method] = resolver
+}
+
+// Methods returns sorted list of all registered DID methods
+func (r *ResolverRegistry) Methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	methods := make([]string, 0, len(r.methods))
+	for m := range r.methods {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Resolve resolves the DID using the resolver registered for DID.Protocol()
+func (r *ResolverRegistry) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	method := did.Protocol()
+	if method == "" {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDID}, nil, newResolutionError(ErrCodeInvalidDID, "missing method: %s", did.String())
+	}
+	r.mu.RLock()
+	resolver, ok := r.methods[method]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, &ResolutionMetadata{Error: ErrCodeMethodNotSupported}, nil, newResolutionError(ErrCodeMethodNotSupported, "%s", method)
+	}
+	return resolver.Resolve(ctx, did)
+}
+
+// ResolveVersion resolves the version of the DID document using the resolver registered for DID.Protocol()
+func (r *ResolverRegistry) ResolveVersion(ctx context.Context, did DID, version VersionSelector) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	r.mu.RLock()
+	resolver, ok := r.methods[did.Protocol()]
+	r.mu.RUnlock()
+	if !ok {
+		return r.Resolve(ctx, did)
+	}
+	return ResolveVersion(ctx, resolver, did, version)
+}
@@ -0,0 +1,35 @@
+package did
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolverRegistry(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewResolverRegistry()
+	registry.Register("mailio", ResolverFunc(func(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+		return doc, &ResolutionMetadata{ContentType: ContentTypeDIDJSON}, &DocumentMetadata{}, nil
+	}))
+	assert.Equal(t, []string{"mailio"}, registry.Methods())
+
+	resolved, rm, _, err := registry.Resolve(context.Background(), doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, doc.ID.String(), resolved.ID.String())
+	assert.Equal(t, ContentTypeDIDJSON, rm.ContentType)
+
+	webDID, _ := ParseDID("did:web:mail.io")
+	_, rm, _, err = registry.Resolve(context.Background(), webDID)
+	assert.ErrorIs(t, err, ErrMethodNotSupported)
+	assert.Equal(t, ErrCodeMethodNotSupported, rm.Error)
+}
@@ -0,0 +1,314 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var mailioAddressRegex = regexp.MustCompile(`^0x[0-9a-f]{40}$`)
+
+// DocumentStore persists did:mailio documents keyed by the Mailio address (DID.Value()).
+// Get returns an error matching ErrNotFound when no document is stored for the address.
+type DocumentStore interface {
+	Get(ctx context.Context, address string) (*Document, error)
+	Put(ctx context.Context, doc *Document) error
+}
+
+// OperationLogStore persists the signed operation logs of did:mailio documents keyed by the Mailio address.
+// GetOperations returns an error matching ErrNotFound when no log is stored for the address.
+// Stores don't verify operations, use PublishOperation to append verified operations only.
+// AppendOperation is a compare-and-append: previous is the OperationHash of the last operation of the log
+// (empty for a new log) and the operation is only appended while it's still the last one, otherwise it fails with ErrConflict.
+// The document resulting from the log with the operation (if not nil) is stored along with the operation in the same
+// step, so DocumentStore.Get never returns a document that doesn't match the end of the log.
+type OperationLogStore interface {
+	GetOperations(ctx context.Context, address string) (OperationLog, error)
+	AppendOperation(ctx context.Context, address string, previous string, operation string, doc *Document) error
+}
+
+// PublishOperation verifies the operation against the stored operation log, appends it and stores the resulting
+// document so it can be read with DocumentStore.Get as well. The store must implement OperationLogStore.
+// When another operation was published concurrently it fails with ErrConflict, rebuild the operation on the new log and retry.
+func PublishOperation(ctx context.Context, store DocumentStore, did DID, operation string) (*Document, *DocumentMetadata, error) {
+	logStore, ok := store.(OperationLogStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T doesn't store operation logs", store)
+	}
+	log, err := logStore.GetOperations(ctx, did.Value())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+	previous := ""
+	if len(log) > 0 {
+		previous = OperationHash(log[len(log)-1])
+	}
+	op, err := ParseOperation(operation)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(log) > 0 && op.Previous != previous {
+		return nil, nil, fmt.Errorf("%w: operation doesn't link to the end of the log", ErrConflict)
+	}
+	log = append(log, operation)
+	doc, md, err := log.Replay(did)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := logStore.AppendOperation(ctx, did.Value(), previous, operation, doc); err != nil {
+		return nil, nil, err
+	}
+	return doc, md, nil
+}
+
+// GetDocumentVersion returns the selected version of the document by replaying the operation log of the store
+// up to the version (OperationLog.ReplayVersion). Documents stored without an operation log only have a current
+// version, their history fails with ErrNotFound. A log failing verification fails with ErrInvalidDIDDocument.
+func GetDocumentVersion(ctx context.Context, store DocumentStore, did DID, version VersionSelector) (*Document, *DocumentMetadata, error) {
+	logStore, ok := store.(OperationLogStore)
+	if !ok {
+		return nil, nil, newResolutionError(ErrCodeNotFound, "%T doesn't store the version history of %s", store, did.String())
+	}
+	log, err := logStore.GetOperations(ctx, did.Value())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(log) == 0 {
+		return nil, nil, newResolutionError(ErrCodeNotFound, "no version history of %s", did.String())
+	}
+	doc, md, err := log.ReplayVersion(did, version)
+	if err != nil && ErrorCode(err) == "" {
+		return nil, nil, invalidDIDDocumentError(err)
+	}
+	return doc, md, err
+}
+
+// MemoryDocumentStore is an in-memory DocumentStore and OperationLogStore safe for concurrent use
+type MemoryDocumentStore struct {
+	mu   sync.RWMutex
+	docs map[string][]byte
+	logs map[string]OperationLog
+}
+
+// NewMemoryDocumentStore creates an empty in-memory document store
+func NewMemoryDocumentStore() *MemoryDocumentStore {
+	return &MemoryDocumentStore{
+		docs: make(map[string][]byte),
+		logs: make(map[string]OperationLog),
+	}
+}
+
+func (s *MemoryDocumentStore) Get(ctx context.Context, address string) (*Document, error) {
+	s.mu.RLock()
+	b, ok := s.docs[address]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, newResolutionError(ErrCodeNotFound, "%s", address)
+	}
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (s *MemoryDocumentStore) Put(ctx context.Context, doc *Document) error {
+	// documents are stored serialized so callers can't modify stored documents
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[doc.ID.Value()] = b
+	return nil
+}
+
+func (s *MemoryDocumentStore) GetOperations(ctx context.Context, address string) (OperationLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	log, ok := s.logs[address]
+	if !ok {
+		return nil, newResolutionError(ErrCodeNotFound, "%s", address)
+	}
+	return append(OperationLog(nil), log...), nil
+}
+
+func (s *MemoryDocumentStore) AppendOperation(ctx context.Context, address string, previous string, operation string, doc *Document) error {
+	var b []byte
+	if doc != nil {
+		var err error
+		if b, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := checkPreviousOperation(s.logs[address], previous); err != nil {
+		return err
+	}
+	s.logs[address] = append(s.logs[address], operation)
+	if b != nil {
+		s.docs[address] = b
+	}
+	return nil
+}
+
+// checkPreviousOperation checks that previous is the OperationHash of the last operation of the log
+func checkPreviousOperation(log OperationLog, previous string) error {
+	last := ""
+	if len(log) > 0 {
+		last = OperationHash(log[len(log)-1])
+	}
+	if last != previous {
+		return fmt.Errorf("%w: log doesn't end with %q", ErrConflict, previous)
+	}
+	return nil
+}
+
+// FileDocumentStore stores each document as <address>.json and its operation log as <address>.log
+// (one operation per line) in a directory. Reads and writes are synchronized within the process,
+// the directory must not be shared by several processes.
+type FileDocumentStore struct {
+	dir string
+	mu  sync.RWMutex // writers hold it while updating the log and the document of an address
+}
+
+// NewFileDocumentStore creates a file based document store. The directory is created if it doesn't exist.
+func NewFileDocumentStore(dir string) (*FileDocumentStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileDocumentStore{
+		dir: dir,
+	}, nil
+}
+
+func (s *FileDocumentStore) path(address string) (string, error) {
+	return s.file(address, ".json")
+}
+
+func (s *FileDocumentStore) file(address string, ext string) (string, error) {
+	if !mailioAddressRegex.MatchString(address) {
+		return "", newResolutionError(ErrCodeInvalidDID, "invalid mailio address: %q", address)
+	}
+	return filepath.Join(s.dir, address+ext), nil
+}
+
+func (s *FileDocumentStore) Get(ctx context.Context, address string) (*Document, error) {
+	p, err := s.path(address)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	b, err := os.ReadFile(p)
+	s.mu.RUnlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, newResolutionError(ErrCodeNotFound, "%s", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc Document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (s *FileDocumentStore) Put(ctx context.Context, doc *Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writeDocument(doc)
+}
+
+// writeDocument writes the document, the caller holds mu
+func (s *FileDocumentStore) writeDocument(doc *Document) error {
+	p, err := s.path(doc.ID.Value())
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so readers never see a partially written document
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (s *FileDocumentStore) GetOperations(ctx context.Context, address string) (OperationLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readOperations(address)
+}
+
+// readOperations reads the operation log, the caller holds mu
+func (s *FileDocumentStore) readOperations(address string) (OperationLog, error) {
+	p, err := s.file(address, ".log")
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, newResolutionError(ErrCodeNotFound, "%s", address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var log OperationLog
+	for _, line := range strings.Split(string(b), "\n") {
+		if line != "" {
+			log = append(log, line)
+		}
+	}
+	return log, nil
+}
+
+func (s *FileDocumentStore) AppendOperation(ctx context.Context, address string, previous string, operation string, doc *Document) error {
+	p, err := s.file(address, ".log")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log, err := s.readOperations(address)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := checkPreviousOperation(log, previous); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(operation + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if doc == nil {
+		return nil
+	}
+	return s.writeDocument(doc)
+}
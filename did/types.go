@@ -1,23 +1,32 @@
 package did
 
 import (
+	"bytes"
 	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/rsa"
 	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/x25519"
 	"github.com/mr-tron/base58"
 )
 
 const (
-	CtxDIDv1             = "https://www.w3.org/ns/did/v1"
-	CtxSecEd25519_2020v1 = "https://w3id.org/security/suites/ed25519-2020/v1"
-	CtxSecX25519_2019v1  = "https://w3id.org/security/suites/x25519-2019/v1"
-	CtxDIDCommMsg_v2     = "https://didcomm.org/messaging/contexts/v2"
+	CtxDIDv1                      = "https://www.w3.org/ns/did/v1"
+	CtxSecEd25519_2020v1          = "https://w3id.org/security/suites/ed25519-2020/v1"
+	CtxSecX25519_2019v1           = "https://w3id.org/security/suites/x25519-2019/v1"
+	CtxDIDCommMsg_v2              = "https://didcomm.org/messaging/contexts/v2"
+	CtxSecJWS2020v1               = "https://w3id.org/security/suites/jws-2020/v1"
+	CtxSecEd25519_2018v1          = "https://w3id.org/security/suites/ed25519-2018/v1"
+	CtxSecSecp256k1Recovery2020v2 = "https://w3id.org/security/suites/secp256k1recovery-2020/v2"
+	CtxSecMultikeyV1              = "https://w3id.org/security/multikey/v1"
 )
 
 type DID struct {
@@ -62,6 +71,8 @@ func (d *DID) Protocol() string {
 	return d.proto
 }
 
+// ParseDID parses a DID or a DID URL. Path and query of a DID URL are kept only in String(),
+// use ParseDIDURL to access them.
 func ParseDID(s string) (DID, error) {
 	// Fragment only DID
 	if strings.HasPrefix(s, "#") {
@@ -71,28 +82,16 @@ func ParseDID(s string) (DID, error) {
 		}, nil
 	}
 
-	dfrag := strings.SplitN(s, "#", 2)
-
-	segm := strings.SplitN(dfrag[0], ":", 3)
-	if len(segm) != 3 {
-		return DID{}, fmt.Errorf("invalid did: must contain three parts: %v", segm)
-	}
-
-	if segm[0] != "did" {
-		return DID{}, fmt.Errorf("invalid did: first segment must be 'did'")
-	}
-
-	var frag string
-	if len(dfrag) == 2 {
-		frag = "#" + dfrag[1]
-		frag = strings.Replace(frag, "#", "", 1)
+	u, err := ParseDIDURL(s)
+	if err != nil {
+		return DID{}, err
 	}
 
 	return DID{
 		raw:      s,
-		proto:    segm[1],
-		value:    segm[2],
-		fragment: frag,
+		proto:    u.DID.proto,
+		value:    u.DID.value,
+		fragment: u.Fragment,
 	}, nil
 }
 
@@ -106,7 +105,15 @@ type Document struct {
 
 	AlsoKnownAs []string `json:"alsoKnownAs,omitempty"`
 
-	Authentication []interface{} `json:"authentication,omitempty"`
+	Controller ControllerSet `json:"controller,omitempty"`
+
+	Authentication []VerificationRelationship `json:"authentication,omitempty"`
+
+	AssertionMethod []VerificationRelationship `json:"assertionMethod,omitempty"`
+
+	CapabilityInvocation []VerificationRelationship `json:"capabilityInvocation,omitempty"`
+
+	CapabilityDelegation []VerificationRelationship `json:"capabilityDelegation,omitempty"`
 
 	VerificationMethod []VerificationMethod `json:"verificationMethod,omitempty"`
 
@@ -130,10 +137,13 @@ type Service struct {
 // For example, a cryptographic public key can be used as a verification method with respect to a
 // digital signature; in such usage, it verifies that the signer possessed the associated cryptographic private key.
 type VerificationMethod struct {
-	ID           string        `json:"id,omitempty"`
-	Type         string        `json:"type,omitempty"`
-	Controller   string        `json:"controller,omitempty"`
-	PublicKeyJwk *PublicKeyJwk `json:"publicKeyJwk,omitempty"`
+	ID                  string        `json:"id,omitempty"`
+	Type                string        `json:"type,omitempty"`
+	Controller          string        `json:"controller,omitempty"`
+	PublicKeyJwk        *PublicKeyJwk `json:"publicKeyJwk,omitempty"`
+	PublicKeyMultibase  string        `json:"publicKeyMultibase,omitempty"`
+	PublicKeyBase58     string        `json:"publicKeyBase58,omitempty"`
+	BlockchainAccountID string        `json:"blockchainAccountId,omitempty"` // CAIP-10 account id (e.g. eip155:1:0xab16a96d359ec26a11e2c2b3d8f8b8942d5bfcdb)
 }
 
 // A set of parameters that can be used together with a process to independently derive a shared key or secret
@@ -146,35 +156,46 @@ type KeyAgreement struct {
 	PublicKeyJwk       *PublicKeyJwk `json:"publicKeyJwk,omitempty"`
 }
 
-// get public key from verification method
+// get public key from verification method (publicKeyJwk, publicKeyMultibase or publicKeyBase58)
+// ed25519 keys are returned as raw []byte, EC keys as *ecdsa.PublicKey or *secp256k1.PublicKey and RSA keys as *rsa.PublicKey
 func (vm VerificationMethod) GetPublicKey() (*crypto.PublicKey, error) {
-	if vm.PublicKeyJwk != nil {
-		// ed25519 supported key (other yet unsupported)
-		jwkKey := vm.PublicKeyJwk.Key
-		if jwkKey == nil {
-			return nil, fmt.Errorf("no key found in jwk")
-		}
-		keyType := jwkKey.KeyType()
-		switch keyType {
-		case jwa.OKP:
-
-			k, err := vm.PublicKeyJwk.GetRawKey()
-			if err != nil {
-				return nil, err
-			}
-			ek, ok := k.(ed25519.PublicKey)
-			if !ok {
-				return nil, fmt.Errorf("only ed25519 keys are currently supported")
-			}
-			pkRaw := []byte(ek)
-			publicKey := crypto.PublicKey(pkRaw)
-			return &publicKey, nil
-		default:
-			return nil, fmt.Errorf("unsupported key type: %s", keyType)
-		}
+	var (
+		key crypto.PublicKey
+		err error
+	)
+	switch {
+	case vm.PublicKeyJwk != nil:
+		key, err = vm.PublicKeyJwk.GetPublicKey()
+	case vm.PublicKeyMultibase != "":
+		key, err = DecodePublicKeyMultibase(vm.PublicKeyMultibase)
+	case vm.PublicKeyBase58 != "":
+		key, err = vm.base58PublicKey()
+	default:
+		return nil, fmt.Errorf("no public key specified in verificationMethod: %w", ErrKeyNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ek, ok := key.(ed25519.PublicKey); ok {
+		key = []byte(ek)
 	}
+	return &key, nil
+}
 
-	return nil, fmt.Errorf("no public key specified in verificationMethod")
+// base58PublicKey decodes publicKeyBase58 which carries the raw key without multicodec header, the key type is
+// known from the verification method type only
+func (vm VerificationMethod) base58PublicKey() (crypto.PublicKey, error) {
+	raw, err := base58.Decode(vm.PublicKeyBase58)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publicKeyBase58: %w", err)
+	}
+	switch vm.Type {
+	case KeyTypeEd25519_2018, KeyTypeEd25519:
+		return parseMulticodecPublicKey(MCed25519, raw)
+	case KeyTypeEcdsaSecp256k1_2019:
+		return parseMulticodecPublicKey(MCsecp256k1, raw)
+	}
+	return nil, fmt.Errorf("%w: publicKeyBase58 of %q", ErrUnsupportedKeyType, vm.Type)
 }
 
 type PublicKeyJwk struct {
@@ -205,6 +226,34 @@ func (pkj *PublicKeyJwk) MarshalJSON() ([]byte, error) {
 	return json.Marshal(pkj.Key)
 }
 
+// GetPublicKey returns the signature verification key of the jwk: ed25519.PublicKey, *ecdsa.PublicKey,
+// *secp256k1.PublicKey or *rsa.PublicKey
+func (pk *PublicKeyJwk) GetPublicKey() (crypto.PublicKey, error) {
+	if pk.Key == nil {
+		return nil, fmt.Errorf("no key found in jwk: %w", ErrKeyNotFound)
+	}
+	// jwx only supports secp256k1 with the jwx_es256k build tag
+	if ek, ok := pk.Key.(jwk.ECDSAPublicKey); ok && ek.Crv().String() == "secp256k1" {
+		if len(ek.X()) != 32 || len(ek.Y()) != 32 {
+			return nil, fmt.Errorf("invalid secp256k1 jwk: coordinates must be 32 bytes, got x %d y %d", len(ek.X()), len(ek.Y()))
+		}
+		point := make([]byte, 65)
+		point[0] = 0x04
+		copy(point[1:33], ek.X())
+		copy(point[33:], ek.Y())
+		return secp256k1.ParsePubKey(point)
+	}
+	raw, err := pk.GetRawKey()
+	if err != nil {
+		return nil, err
+	}
+	switch k := raw.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: %s %T", ErrUnsupportedKeyType, pk.Key.KeyType(), raw)
+}
+
 func (pk *PublicKeyJwk) GetRawKey() (interface{}, error) {
 	var rawkey interface{}
 	if err := pk.Key.Raw(&rawkey); err != nil {
@@ -216,28 +265,154 @@ func (pk *PublicKeyJwk) GetRawKey() (interface{}, error) {
 
 // get public key by finding a correct verification method and returning the public key
 func (d *Document) GetVerificationPublicKey(id string) (*crypto.PublicKey, error) {
-	for _, vm := range d.VerificationMethod {
-		if id == vm.ID || id == "" {
-			return vm.GetPublicKey()
+	vm, err := d.FindVerificationMethod(id)
+	if err != nil {
+		return nil, err
+	}
+	return vm.GetPublicKey()
+}
+
+// AbsoluteID resolves a relative DID URL (e.g. "#master") against the document ID.
+// Absolute ids are returned unchanged.
+func (d *Document) AbsoluteID(id string) string {
+	if strings.HasPrefix(id, "#") {
+		return "did:" + d.ID.Protocol() + ":" + d.ID.Value() + id
+	}
+	return id
+}
+
+func (d *Document) sameID(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	return d.AbsoluteID(a) == d.AbsoluteID(b)
+}
+
+// FindVerificationMethod finds the verification method by its absolute or relative (fragment only) id
+func (d *Document) FindVerificationMethod(id string) (*VerificationMethod, error) {
+	for i := range d.VerificationMethod {
+		if d.sameID(id, d.VerificationMethod[i].ID) {
+			return &d.VerificationMethod[i], nil
 		}
 	}
+	return nil, fmt.Errorf("no verification method found by ID %q: %w", id, ErrKeyNotFound)
+}
 
-	return nil, fmt.Errorf("no key found by that ID")
+// FindKeyAgreement finds the key agreement method by its absolute or relative (fragment only) id.
+// Referenced verification methods are returned as key agreement methods.
+func (d *Document) FindKeyAgreement(id string) (*KeyAgreement, error) {
+	for i := range d.KeyAgreement {
+		if !d.sameID(id, d.KeyAgreement[i].ID) {
+			continue
+		}
+		if !d.KeyAgreement[i].IsReference() {
+			return &d.KeyAgreement[i], nil
+		}
+		vm, err := d.FindVerificationMethod(id)
+		if err != nil {
+			return nil, err
+		}
+		return &KeyAgreement{
+			ID:                 vm.ID,
+			Type:               vm.Type,
+			Controller:         vm.Controller,
+			PublicKeyMultibase: vm.PublicKeyMultibase,
+			PublicKeyJwk:       vm.PublicKeyJwk,
+		}, nil
+	}
+	return nil, fmt.Errorf("no key agreement found by ID %q: %w", id, ErrKeyNotFound)
 }
 
-// GetPublicKey for an KeyAgreement
-func (ka *KeyAgreement) GetPublicKey() (*crypto.PublicKey, error) {
-	if ka.PublicKeyMultibase == "" {
-		return nil, fmt.Errorf("no public key specified in keyAgreement")
+// FindService finds the service by its absolute or relative (fragment only) id
+func (d *Document) FindService(id string) (*Service, error) {
+	for i := range d.Service {
+		if d.sameID(id, d.Service[i].ID) {
+			return &d.Service[i], nil
+		}
+	}
+	return nil, newResolutionError(ErrCodeNotFound, "no service found by ID %q", id)
+}
+
+// NewReferenceKeyAgreement references a verification method of the document as key agreement method
+// (e.g. "#0" of did:jwk documents)
+func NewReferenceKeyAgreement(id string) KeyAgreement {
+	return KeyAgreement{ID: id}
+}
+
+// IsReference reports whether the key agreement only references a verification method by its id
+func (ka KeyAgreement) IsReference() bool {
+	return ka.Type == "" && ka.Controller == "" && ka.PublicKeyMultibase == "" && ka.PublicKeyJwk == nil
+}
+
+// MarshalJSON marshals references as plain id strings and embedded methods as objects
+func (ka KeyAgreement) MarshalJSON() ([]byte, error) {
+	if ka.IsReference() {
+		return json.Marshal(ka.ID)
+	}
+	type keyAgreement KeyAgreement
+	return json.Marshal(keyAgreement(ka))
+}
+
+// UnmarshalJSON accepts both a reference (id string) and an embedded key agreement method
+func (ka *KeyAgreement) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var id string
+		if err := json.Unmarshal(b, &id); err != nil {
+			return err
+		}
+		*ka = NewReferenceKeyAgreement(id)
+		return nil
 	}
-	decoded, err := base58.Decode(ka.PublicKeyMultibase)
+	type keyAgreement KeyAgreement
+	var embedded keyAgreement
+	if err := json.Unmarshal(b, &embedded); err != nil {
+		return err
+	}
+	*ka = KeyAgreement(embedded)
+	return nil
+}
+
+// GetPublicKey for an KeyAgreement. X25519 keys are returned as *ecdh.PublicKey.
+func (ka *KeyAgreement) GetPublicKey() (*crypto.PublicKey, error) {
+	xk, err := ka.ECDHPublicKey()
 	if err != nil {
 		return nil, err
 	}
-	publicKey := crypto.PublicKey(decoded)
+	publicKey := crypto.PublicKey(xk)
 	return &publicKey, nil
 }
 
+// ECDHPublicKey returns the X25519 key of the key agreement, ready to be used with ecdh.PrivateKey.ECDH.
+// publicKeyJwk takes precedence over publicKeyMultibase.
+func (ka *KeyAgreement) ECDHPublicKey() (*ecdh.PublicKey, error) {
+	switch {
+	case ka.PublicKeyJwk != nil:
+		k, err := ka.PublicKeyJwk.GetRawKey()
+		if err != nil {
+			return nil, err
+		}
+		xk, ok := k.(x25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("only x25519 keys are currently supported: %w", ErrUnsupportedKeyType)
+		}
+		return ecdh.X25519().NewPublicKey(xk)
+	case ka.PublicKeyMultibase != "":
+		if k, err := DecodePublicKeyMultibase(ka.PublicKeyMultibase); err == nil {
+			if xk, ok := k.(*ecdh.PublicKey); ok && xk.Curve() == ecdh.X25519() {
+				return xk, nil
+			}
+		}
+		// legacy documents store the raw key base58 encoded without multibase prefix and multicodec header
+		decoded, err := base58.Decode(ka.PublicKeyMultibase)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publicKeyMultibase: %w", err)
+		}
+		return ecdh.X25519().NewPublicKey(decoded)
+	}
+	return nil, fmt.Errorf("no public key specified in keyAgreement: %w", ErrKeyNotFound)
+}
+
 // VerifiableCredential is a JSON-LD document that cryptographically proves that the subject
 // identified by the DID has been verified against a given credential schema.
 // The Verifiable Credential data model is defined in the W3C Verifiable Credentials Data Model 1.0 specification.
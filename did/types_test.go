@@ -1,6 +1,10 @@
 package did
 
-import "testing"
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
 
 func TestParseDID(t *testing.T) {
 	did, err := ParseDID("did:mailio:1234")
@@ -36,3 +40,30 @@ func TestParseWebDID(t *testing.T) {
 		t.Fatal("invalid fragment")
 	}
 }
+
+func TestParseInvalidDID(t *testing.T) {
+	_, err := ParseDID("mailio:1234")
+	if !errors.Is(err, ErrInvalidDID) {
+		t.Fatalf("expected invalidDid error, got %v", err)
+	}
+	if ErrorCode(err) != ErrCodeInvalidDID {
+		t.Fatal("invalid error code")
+	}
+}
+
+func TestSecp256k1JwkCoordinateSize(t *testing.T) {
+	coordinate := base64.RawURLEncoding.EncodeToString(make([]byte, 40))
+	var pk PublicKeyJwk
+	err := pk.UnmarshalJSON([]byte(`{"kty":"EC","crv":"secp256k1","x":"` + coordinate + `","y":"` + coordinate + `"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pk.GetPublicKey(); err == nil {
+		t.Fatal("expected error for oversized secp256k1 coordinates")
+	}
+
+	vm := VerificationMethod{ID: "#k1", Type: KeyTypeEcdsaSecp256k1_2019, PublicKeyJwk: &pk}
+	if _, err := vm.GetPublicKey(); err == nil {
+		t.Fatal("expected error for oversized secp256k1 coordinates")
+	}
+}
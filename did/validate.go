@@ -0,0 +1,259 @@
+package did
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+)
+
+// Validate checks the document against the DID Core conformance rules (https://www.w3.org/TR/did-core/#conformance):
+//   - @context starts with CtxDIDv1
+//   - the document ID is a DID without path, query or fragment
+//   - verification method and key agreement ids are unique and either absolute DID URLs or relative fragments
+//   - document and verification method controllers are valid DIDs
+//   - verification relationship references exist in VerificationMethod
+//   - service ids are unique URIs
+//   - key material matches the declared verification method type
+//
+// All problems found are returned joined, each of them matching ErrInvalidDocument.
+func (d *Document) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidDocument, fmt.Sprintf(format, args...)))
+	}
+
+	if len(d.Context) == 0 || d.Context[0] != CtxDIDv1 {
+		invalid("@context must start with %s", CtxDIDv1)
+	}
+	if u, err := ParseDIDURL(d.ID.String()); err != nil {
+		invalid("invalid id %q: %v", d.ID.String(), err)
+	} else if u.Path != "" || u.Query != "" || u.hasQuery || u.Fragment != "" || u.hasFragment {
+		invalid("id %q must not contain path, query or fragment", d.ID.String())
+	}
+
+	ids := make(map[string]bool)
+	checkID := func(kind string, id string) {
+		if id == "" {
+			invalid("%s without id", kind)
+			return
+		}
+		if !strings.HasPrefix(id, "#") {
+			u, err := ParseDIDURL(id)
+			if err != nil {
+				invalid("invalid %s id %q: %v", kind, id, err)
+				return
+			}
+			if u.Fragment == "" {
+				invalid("%s id %q must be a DID URL with a fragment", kind, id)
+				return
+			}
+		} else if len(id) == 1 {
+			invalid("empty %s fragment", kind)
+			return
+		}
+		abs := d.AbsoluteID(id)
+		if ids[abs] {
+			invalid("duplicate id %q", id)
+		}
+		ids[abs] = true
+	}
+	checkController := func(kind string, id string, controller string) {
+		if controller == "" {
+			return
+		}
+		if u, err := ParseDIDURL(controller); err != nil || u.Fragment != "" {
+			invalid("%s %q has invalid controller %q", kind, id, controller)
+		}
+	}
+
+	for _, controller := range d.Controller {
+		checkController("document", d.ID.String(), controller)
+	}
+
+	for _, vm := range d.VerificationMethod {
+		checkID("verification method", vm.ID)
+		checkController("verification method", vm.ID, vm.Controller)
+		if err := vm.checkKeyMaterial(); err != nil {
+			invalid("verification method %q: %v", vm.ID, err)
+		}
+	}
+	for _, ka := range d.KeyAgreement {
+		if ka.IsReference() {
+			resolved, err := d.FindKeyAgreement(ka.ID)
+			if err != nil {
+				invalid("keyAgreement references unknown verification method %q", ka.ID)
+			} else if err := resolved.checkKeyMaterial(); err != nil {
+				invalid("key agreement %q: %v", ka.ID, err)
+			}
+			continue
+		}
+		checkID("key agreement", ka.ID)
+		checkController("key agreement", ka.ID, ka.Controller)
+		if err := ka.checkKeyMaterial(); err != nil {
+			invalid("key agreement %q: %v", ka.ID, err)
+		}
+	}
+
+	for _, relationship := range []string{RelationshipAuthentication, RelationshipAssertionMethod, RelationshipCapabilityInvocation, RelationshipCapabilityDelegation} {
+		for _, r := range d.Relationship(relationship) {
+			if r.IsReference() {
+				if _, err := d.FindVerificationMethod(r.Reference); err != nil {
+					invalid("%s references unknown verification method %q", relationship, r.Reference)
+				}
+				continue
+			}
+			vm := r.Method
+			checkID(relationship+" method", vm.ID)
+			checkController(relationship+" method", vm.ID, vm.Controller)
+			if err := vm.checkKeyMaterial(); err != nil {
+				invalid("%s method %q: %v", relationship, vm.ID, err)
+			}
+		}
+	}
+
+	serviceIDs := make(map[string]bool)
+	for _, s := range d.Service {
+		if s.ID == "" {
+			invalid("service without id")
+			continue
+		}
+		if u, err := url.Parse(s.ID); err != nil || (u.Scheme == "" && !strings.HasPrefix(s.ID, "#")) {
+			invalid("service id %q must be a URI", s.ID)
+		}
+		abs := d.AbsoluteID(s.ID)
+		if serviceIDs[abs] {
+			invalid("duplicate service id %q", s.ID)
+		}
+		serviceIDs[abs] = true
+		if s.Type == "" {
+			invalid("service %q without type", s.ID)
+		}
+		if s.ServiceEndpoint == "" {
+			invalid("service %q without serviceEndpoint", s.ID)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkKeyMaterial checks that the verification method carries the key material its type requires
+// and that the key is of the algorithm the type declares
+func (vm *VerificationMethod) checkKeyMaterial() error {
+	switch vm.Type {
+	case "":
+		return fmt.Errorf("missing type")
+	case PublicKeyJwkType:
+		if vm.PublicKeyJwk == nil || vm.PublicKeyJwk.Key == nil {
+			return fmt.Errorf("%s requires publicKeyJwk", vm.Type)
+		}
+		// signing keys and X25519 keys (did:jwk with "use":"enc") are published as JsonWebKey2020
+		if _, err := vm.GetPublicKey(); err != nil {
+			if raw, rawErr := vm.PublicKeyJwk.GetRawKey(); rawErr != nil || !isX25519Key(raw) {
+				return fmt.Errorf("%s: %v", vm.Type, err)
+			}
+		}
+	case KeyTypeEd25519_2018:
+		if vm.PublicKeyBase58 == "" && vm.BlockchainAccountID == "" {
+			return fmt.Errorf("%s requires publicKeyBase58", vm.Type)
+		}
+		if vm.PublicKeyBase58 != "" {
+			return vm.checkKeyType(isEd25519Key, "ed25519")
+		}
+	case KeyTypeMultikey, KeyTypeEd25519:
+		if vm.PublicKeyMultibase == "" && vm.PublicKeyBase58 == "" {
+			return fmt.Errorf("%s requires publicKeyMultibase", vm.Type)
+		}
+		if vm.PublicKeyMultibase != "" && vm.PublicKeyMultibase[0] != MultibaseBase58BTC {
+			return fmt.Errorf("%s publicKeyMultibase must be base58btc encoded", vm.Type)
+		}
+		if vm.Type == KeyTypeEd25519 {
+			return vm.checkKeyType(isEd25519Key, "ed25519")
+		}
+		if _, err := vm.GetPublicKey(); err != nil {
+			return fmt.Errorf("%s: %v", vm.Type, err)
+		}
+	case KeyTypeEcdsaSecp256k1_2019:
+		if vm.PublicKeyJwk == nil && vm.PublicKeyMultibase == "" && vm.PublicKeyBase58 == "" {
+			return fmt.Errorf("%s requires publicKeyJwk, publicKeyMultibase or publicKeyBase58", vm.Type)
+		}
+		return vm.checkKeyType(isSecp256k1Key, "secp256k1")
+	case KeyTypeEcdsaSecp256k1Recovery2020:
+		if vm.BlockchainAccountID == "" && vm.PublicKeyJwk == nil {
+			return fmt.Errorf("%s requires blockchainAccountId or publicKeyJwk", vm.Type)
+		}
+		if vm.PublicKeyJwk != nil {
+			return vm.checkKeyType(isSecp256k1Key, "secp256k1")
+		}
+	}
+	return nil
+}
+
+// checkKeyType decodes the public key and checks it with the algorithm predicate
+func (vm *VerificationMethod) checkKeyType(is func(key interface{}) bool, algorithm string) error {
+	publicKey, err := vm.GetPublicKey()
+	if err != nil {
+		return fmt.Errorf("%s: %v", vm.Type, err)
+	}
+	if !is(*publicKey) {
+		return fmt.Errorf("%s requires a %s key, got %T", vm.Type, algorithm, *publicKey)
+	}
+	return nil
+}
+
+// checkKeyMaterial checks that the key agreement carries the key material its type requires
+// and that the key can be used for key agreement
+func (ka *KeyAgreement) checkKeyMaterial() error {
+	switch ka.Type {
+	case "":
+		return fmt.Errorf("missing type")
+	case PublicKeyJwkType:
+		if ka.PublicKeyJwk == nil || ka.PublicKeyJwk.Key == nil {
+			return fmt.Errorf("%s requires publicKeyJwk", ka.Type)
+		}
+		raw, err := ka.PublicKeyJwk.GetRawKey()
+		if err != nil {
+			return fmt.Errorf("%s: %v", ka.Type, err)
+		}
+		if _, ok := raw.(*ecdsa.PublicKey); !ok && !isX25519Key(raw) {
+			return fmt.Errorf("%s key agreement requires an X25519 or EC key, got %T", ka.Type, raw)
+		}
+	case KeyTypeX25519KeyAgreement:
+		if ka.PublicKeyMultibase == "" && ka.PublicKeyJwk == nil {
+			return fmt.Errorf("%s requires publicKeyMultibase or publicKeyJwk", ka.Type)
+		}
+		if _, err := ka.ECDHPublicKey(); err != nil {
+			return fmt.Errorf("%s requires an x25519 key: %v", ka.Type, err)
+		}
+	}
+	return nil
+}
+
+func isEd25519Key(key interface{}) bool {
+	switch key.(type) {
+	case ed25519.PublicKey, []byte:
+		return true
+	}
+	return false
+}
+
+func isSecp256k1Key(key interface{}) bool {
+	_, ok := key.(*secp256k1.PublicKey)
+	return ok
+}
+
+func isX25519Key(key interface{}) bool {
+	switch k := key.(type) {
+	case x25519.PublicKey:
+		return true
+	case *ecdh.PublicKey:
+		return k.Curve() == ecdh.X25519()
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package did
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMailioDocument(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	authKey, _ := GenerateMailioPublicKeys()
+	mk.VerificationKeys = []*Key{authKey.MasterSignKey}
+	mk.AuthenticationKeys = []*Key{authKey.MasterSignKey}
+	doc, err := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, doc.Validate())
+}
+
+func TestValidateInvalidDocument(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+
+	doc.Context = []string{CtxSecEd25519_2020v1}
+	doc.KeyAgreement[0].ID = doc.ID.String()
+	doc.VerificationMethod = append(doc.VerificationMethod, doc.VerificationMethod[0])
+	doc.Authentication = append(doc.Authentication, NewReferenceRelationship("#missing"))
+	doc.Service[1].ID = doc.Service[0].ID
+	doc.VerificationMethod[0].Controller = "mailio"
+
+	err := doc.Validate()
+	assert.ErrorIs(t, err, ErrInvalidDocument)
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) {
+		t.Fatal("expected joined errors")
+	}
+	// context, controller, duplicate vm id, key agreement id, unknown authentication, duplicate service
+	assert.Len(t, joined.Unwrap(), 6)
+}
+
+func TestValidateKeyTypeMismatch(t *testing.T) {
+	mk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	xk, _, _ := CreateX25519Keys()
+	x25519Multibase, _ := EncodePublicKeyMultibase(xk)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p256Jwk, _ := publicKeyToJwk(&p256.PublicKey)
+	edJwk, _ := publicKeyToJwk(mk.MasterSignKey.PublicKey)
+
+	mismatched := map[string]func(doc *Document){
+		"x25519 as Ed25519VerificationKey2020": func(doc *Document) {
+			doc.VerificationMethod[0] = VerificationMethod{ID: doc.VerificationMethod[0].ID, Type: KeyTypeEd25519, Controller: doc.ID.String(), PublicKeyMultibase: x25519Multibase}
+		},
+		"p-256 as EcdsaSecp256k1VerificationKey2019": func(doc *Document) {
+			doc.VerificationMethod[0].Type = KeyTypeEcdsaSecp256k1_2019
+			doc.VerificationMethod[0].PublicKeyJwk = p256Jwk
+		},
+		"p-256 as X25519KeyAgreementKey2019": func(doc *Document) {
+			doc.KeyAgreement[0].PublicKeyMultibase = ""
+			doc.KeyAgreement[0].PublicKeyJwk = p256Jwk
+		},
+		"ed25519 JsonWebKey2020 key agreement": func(doc *Document) {
+			doc.KeyAgreement[0].Type = PublicKeyJwkType
+			doc.KeyAgreement[0].PublicKeyMultibase = ""
+			doc.KeyAgreement[0].PublicKeyJwk = edJwk
+		},
+	}
+	for name, mismatch := range mismatched {
+		doc, _ := NewMailioDIDDocument(mk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+		assert.NoError(t, doc.Validate(), name)
+		mismatch(doc)
+		assert.ErrorIs(t, doc.Validate(), ErrInvalidDocument, name)
+	}
+}
@@ -1,8 +1,10 @@
 package did
 
 import (
+	"context"
 	"crypto/ed25519"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/fxamacker/cbor/v2"
@@ -10,6 +12,12 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jws"
 )
 
+// credentials are signed with nanosecond timestamps, so Proof.Created survives the signed payload unchanged
+var credentialEncMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+
+// ErrInvalidProofPurpose is returned when the proof purpose doesn't match the verification relationship of the signing key
+var ErrInvalidProofPurpose = errors.New("invalid proof purpose")
+
 func NewVerifiableCredential(mailioDID string) *VerifiableCredential {
 	return &VerifiableCredential{
 		Context:      []string{"https://www.w3.org/2018/credentials/v1"},
@@ -19,26 +27,38 @@ func NewVerifiableCredential(mailioDID string) *VerifiableCredential {
 	}
 }
 
-// CreateProof creates a proof for Verifiable Credential using private key from a signer
+// CreateProof creates a proof for Verifiable Credential using private key from a signer.
+// The proof references the issuer DID instead of one of its verification methods, so it can only be checked with
+// VerifyProof and the public key; VerifyProofWithIssuer and VerifyProofWithResolver always refuse it.
+//
+// Deprecated: use CreateProofWithMethod with the id of an assertionMethod of the issuer (e.g. did:mailio:0x...#1).
 func (vc *VerifiableCredential) CreateProof(privateKey ed25519.PrivateKey) error {
-	cbor, err := cbor.Marshal(vc)
+	return vc.CreateProofWithMethod(privateKey, vc.Issuer)
+}
+
+// CreateProofWithMethod creates a proof for Verifiable Credential referencing the issuers verification method
+// (e.g. did:mailio:0x...#1) the private key belongs to, so the proof can be checked with VerifyProofWithIssuer.
+// The proof type, creation time, purpose and verification method are signed along with the credential.
+func (vc *VerifiableCredential) CreateProofWithMethod(privateKey ed25519.PrivateKey, verificationMethod string) error {
+	vc.Proof = &Proof{
+		Type:               KeyTypeEd25519,
+		Created:            time.Now().UTC(),
+		ProofPurpose:       RelationshipAssertionMethod,
+		VerificationMethod: verificationMethod,
+	}
+	payload, err := credentialEncMode.Marshal(vc)
 	if err != nil {
+		vc.Proof = nil
 		return err
 	}
 
 	svo := jws.WithKey(jwa.EdDSA, privateKey)
-	signature, err := jws.Sign(cbor, svo)
+	signature, err := jws.Sign(payload, svo)
 	if err != nil {
+		vc.Proof = nil
 		return err
 	}
-
-	vc.Proof = &Proof{
-		Type:               KeyTypeEd25519,
-		Created:            time.Now(),
-		ProofPurpose:       "assertionMethod",
-		VerificationMethod: vc.Issuer,
-		Jws:                string(signature),
-	}
+	vc.Proof.Jws = string(signature)
 	return nil
 }
 
@@ -66,6 +86,76 @@ func (vc *VerifiableCredential) VerifyProof(publicKey ed25519.PublicKey) (bool,
 	if vcVerify.Issuer != vc.Issuer {
 		return false, errors.New("Issuer is not match")
 	}
+	// proofs created before the proof options were signed don't carry them in the payload
+	if signed := vcVerify.Proof; signed != nil {
+		if signed.Type != vc.Proof.Type || !signed.Created.Equal(vc.Proof.Created) ||
+			signed.ProofPurpose != vc.Proof.ProofPurpose || signed.VerificationMethod != vc.Proof.VerificationMethod {
+			return false, fmt.Errorf("%w: proof options don't match the signed proof", ErrInvalidSignature)
+		}
+	}
 
 	return true, nil
 }
+
+// VerifyProofWithIssuer verifies the proof with the key of the issuer document. The proof verification method
+// must be listed in the assertionMethod relationship of the issuer.
+func (vc *VerifiableCredential) VerifyProofWithIssuer(issuer *Document) (bool, error) {
+	if vc.Proof == nil {
+		return false, errors.New("Proof is nil")
+	}
+	if issuer.ID.String() != vc.Issuer {
+		return false, fmt.Errorf("issuer document %q doesn't match issuer %q", issuer.ID.String(), vc.Issuer)
+	}
+	if vc.Proof.ProofPurpose != RelationshipAssertionMethod {
+		return false, fmt.Errorf("%w: %q", ErrInvalidProofPurpose, vc.Proof.ProofPurpose)
+	}
+	vm, err := issuer.FindAssertionMethod(vc.Proof.VerificationMethod)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidProofPurpose, err)
+	}
+	publicKey, err := vm.GetPublicKey()
+	if err != nil {
+		return false, err
+	}
+	raw, ok := (*publicKey).([]byte)
+	if !ok {
+		return false, fmt.Errorf("only ed25519 keys are currently supported: %w", ErrUnsupportedKeyType)
+	}
+	return vc.VerifyProof(ed25519.PublicKey(raw))
+}
+
+// VerifyProofWithResolver verifies the proof against the issuer documents resolved with the resolver.
+//
+// The verification method must be an assertionMethod of the current issuer document and, when the document changed
+// since Proof.Created, also of the document version valid at Proof.Created (Proof.Created is signed along with the
+// credential). Methods removed or rotated since are refused even for proofs created while they were valid: a removed
+// key may have been compromised and its holder could sign a backdated proof.
+// Proofs of deactivated issuers are refused with ErrDeactivated.
+func (vc *VerifiableCredential) VerifyProofWithResolver(ctx context.Context, resolver Resolver) (bool, error) {
+	if vc.Proof == nil {
+		return false, errors.New("Proof is nil")
+	}
+	issuerDID, err := ParseDID(vc.Issuer)
+	if err != nil {
+		return false, err
+	}
+	issuer, _, md, err := resolver.Resolve(ctx, issuerDID)
+	if err != nil {
+		return false, err
+	}
+	if md != nil && md.Deactivated {
+		return false, fmt.Errorf("%w: %s", ErrDeactivated, vc.Issuer)
+	}
+	if ok, err := vc.VerifyProofWithIssuer(issuer); !ok || err != nil {
+		return ok, err
+	}
+	if md == nil || md.Updated == nil || !md.Updated.After(vc.Proof.Created) {
+		return true, nil
+	}
+	// the method must have been valid when the proof was created as well
+	historic, _, _, err := ResolveVersion(ctx, resolver, issuerDID, VersionSelector{VersionTime: vc.Proof.Created})
+	if err != nil {
+		return false, err
+	}
+	return vc.VerifyProofWithIssuer(historic)
+}
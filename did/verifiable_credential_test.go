@@ -7,13 +7,15 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewVerifiableCredential(t *testing.T) {
 	serverMk, _ := GenerateMailioPublicKeys()
 	targetAppMk, _ := GenerateMailioPublicKeys()
 	userMk, _ := GenerateMailioPublicKeys()
-	vc := NewVerifiableCredential(serverMk.DID())
+	vc := NewVerifiableCredential(mailioDID(t, serverMk))
 	if vc == nil {
 		t.Fatal("vc is nil")
 	}
@@ -21,9 +23,9 @@ func TestNewVerifiableCredential(t *testing.T) {
 	vc.ID = "http://example.edu/credentials/3732"
 
 	credentialSubject := CredentialSubject{
-		ID: userMk.DID(),
+		ID: mailioDID(t, userMk),
 		AuthorizedApplication: &AuthorizedApplication{
-			ID:           targetAppMk.DID(),
+			ID:           mailioDID(t, targetAppMk),
 			Domains:      []string{"example.com"},
 			ApprovalDate: time.Now(),
 		},
@@ -56,3 +58,41 @@ func TestNewVerifiableCredential(t *testing.T) {
 		t.Fatal("vcVerify is false")
 	}
 }
+
+func TestVerifyProofWithIssuer(t *testing.T) {
+	issuerMk, _ := GenerateMailioPublicKeys()
+	mkMailio, _ := GenerateMailioPublicKeys()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	issuerMk.VerificationKeys = []*Key{{Type: KeyTypeEd25519, PublicKey: publicKey}}
+	issuer, err := NewMailioDIDDocument(issuerMk, mkMailio.MasterSignKey.PublicKey, AuthServiceEndpoint, MessageServiceEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []VerificationRelationship{NewReferenceRelationship("#1")}, issuer.AssertionMethod)
+
+	vc := NewVerifiableCredential(mailioDID(t, issuerMk))
+	vc.CredentialSubject = CredentialSubject{ID: mailioDID(t, issuerMk)}
+	if err := vc.CreateProofWithMethod(privateKey, mailioDID(t, issuerMk)+"#1"); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := vc.VerifyProofWithIssuer(issuer)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the master key is an authentication key, it's not authorized to issue credentials
+	vc.Proof.VerificationMethod = mailioDID(t, issuerMk) + MasterKeyFragment
+	_, err = vc.VerifyProofWithIssuer(issuer)
+	assert.ErrorIs(t, err, ErrInvalidProofPurpose)
+
+	vc.Proof.VerificationMethod = mailioDID(t, issuerMk) + "#1"
+	vc.Proof.ProofPurpose = RelationshipAuthentication
+	_, err = vc.VerifyProofWithIssuer(issuer)
+	assert.ErrorIs(t, err, ErrInvalidProofPurpose)
+
+	// proofs referencing the bare issuer DID aren't verifiable with the issuer document
+	if err := vc.CreateProof(privateKey); err != nil {
+		t.Fatal(err)
+	}
+	_, err = vc.VerifyProofWithIssuer(issuer)
+	assert.Error(t, err)
+}
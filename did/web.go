@@ -0,0 +1,147 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DIDMethodWeb = "web"
+
+	// maximum size of a did.json document fetched by the WebResolver
+	maxWebDocumentSize = 1 << 20
+)
+
+// WebResolver resolves did:web DIDs (https://w3c-ccg.github.io/did-method-web/) by fetching did.json over HTTPS
+type WebResolver struct {
+	Client *http.Client
+}
+
+// NewWebResolver creates a did:web resolver. If client is nil http.DefaultClient is used.
+// Tests can inject the client of an httptest.Server to resolve documents offline.
+func NewWebResolver(client *http.Client) *WebResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebResolver{
+		Client: client,
+	}
+}
+
+// WebDocumentURL maps a did:web DID to the URL of its did.json:
+//
+//	did:web:mail.io                   -> https://mail.io/.well-known/did.json
+//	did:web:mail.io%3A8443            -> https://mail.io:8443/.well-known/did.json
+//	did:web:mail.io:users:alice       -> https://mail.io/users/alice/did.json
+func WebDocumentURL(did DID) (string, error) {
+	if did.Protocol() != DIDMethodWeb {
+		return "", newResolutionError(ErrCodeMethodNotSupported, "not a did:web: %s", did.String())
+	}
+	segments := strings.Split(did.Value(), ":")
+	host, err := url.PathUnescape(segments[0])
+	if err != nil {
+		return "", newResolutionError(ErrCodeInvalidDID, "invalid did:web host %q: %v", segments[0], err)
+	}
+	if host == "" || strings.ContainsAny(host, "/?#@") {
+		return "", newResolutionError(ErrCodeInvalidDID, "invalid did:web host %q", host)
+	}
+
+	u := &url.URL{
+		Scheme: "https",
+		Host:   host,
+	}
+	if len(segments) == 1 {
+		u.Path = "/.well-known/did.json"
+	} else {
+		path := make([]string, 0, len(segments)-1)
+		for _, s := range segments[1:] {
+			p, pErr := url.PathUnescape(s)
+			if pErr != nil {
+				return "", newResolutionError(ErrCodeInvalidDID, "invalid did:web path segment %q: %v", s, pErr)
+			}
+			path = append(path, p)
+		}
+		u.Path = "/" + strings.Join(path, "/") + "/did.json"
+	}
+	return u.String(), nil
+}
+
+// Resolve fetches the did.json of the did:web and validates that the document ID matches the requested DID
+func (r *WebResolver) Resolve(ctx context.Context, did DID) (*Document, *ResolutionMetadata, *DocumentMetadata, error) {
+	docURL, err := WebDocumentURL(did)
+	if err != nil {
+		return nil, &ResolutionMetadata{Error: ErrorCode(err)}, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	req.Header.Set("Accept", ContentTypeDIDJSON+", application/json")
+
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to fetch %s: %w", docURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, &ResolutionMetadata{Error: ErrCodeNotFound}, nil, newResolutionError(ErrCodeNotFound, "%s", docURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &ResolutionMetadata{Error: ErrCodeInternalError}, nil, newResolutionError(ErrCodeInternalError, "failed to fetch %s: unexpected status %d", docURL, resp.StatusCode)
+	}
+
+	// read one byte more than allowed to tell a document of exactly the maximum size from a larger one
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebDocumentSize+1))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(body) > maxWebDocumentSize {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDIDDocument}, nil, newResolutionError(ErrCodeInvalidDIDDocument, "document at %s too large, exceeds %d bytes", docURL, maxWebDocumentSize)
+	}
+	var doc Document
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, &ResolutionMetadata{Error: ErrCodeRepresentationNotSupported}, nil, newResolutionError(ErrCodeRepresentationNotSupported, "invalid did document at %s: %v", docURL, err)
+	}
+
+	expected := "did:" + did.Protocol() + ":" + did.Value()
+	if doc.ID.String() != expected {
+		return nil, &ResolutionMetadata{Error: ErrCodeInvalidDIDDocument}, nil, newResolutionError(ErrCodeInvalidDIDDocument, "document id %q does not match %q", doc.ID.String(), expected)
+	}
+
+	contentType := ContentTypeDIDJSON
+	if ct, _, ctErr := mime.ParseMediaType(resp.Header.Get("Content-Type")); ctErr == nil && ct == ContentTypeDIDLDJSON {
+		contentType = ct
+	}
+	return &doc, &ResolutionMetadata{ContentType: contentType, Expires: httpCacheExpiry(resp.Header, time.Now())}, &DocumentMetadata{}, nil
+}
+
+// httpCacheExpiry returns until when the response may be cached according to the Cache-Control and Expires headers
+// or nil if the headers don't say
+func httpCacheExpiry(header http.Header, now time.Time) *time.Time {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store" || directive == "no-cache":
+			return &now
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				expires := now.Add(time.Duration(seconds) * time.Second)
+				return &expires
+			}
+		}
+	}
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return &expires
+	}
+	return nil
+}
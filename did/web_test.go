@@ -0,0 +1,92 @@
+package did
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDocumentURL(t *testing.T) {
+	tests := map[string]string{
+		"did:web:mail.io":             "https://mail.io/.well-known/did.json",
+		"did:web:mail.io%3A8443":      "https://mail.io:8443/.well-known/did.json",
+		"did:web:mail.io:users:alice": "https://mail.io/users/alice/did.json",
+	}
+	for d, expected := range tests {
+		did, err := ParseDID(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := WebDocumentURL(did)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, expected, u)
+	}
+}
+
+func TestWebResolver(t *testing.T) {
+	var webDID string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/alice/did.json":
+			did, _ := ParseDID(webDID)
+			w.Header().Set("Content-Type", ContentTypeDIDJSON)
+			json.NewEncoder(w).Encode(&Document{Context: []string{CtxDIDv1}, ID: did})
+		case "/users/large/did.json":
+			w.Write([]byte(`{"id":"` + strings.Repeat(" ", maxWebDocumentSize) + `"}`))
+		case "/users/mallory/did.json":
+			did, _ := ParseDID("did:web:mail.io:users:mallory")
+			json.NewEncoder(w).Encode(&Document{Context: []string{CtxDIDv1}, ID: did})
+		case "/users/broken/did.json":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	host := strings.ReplaceAll(serverURL.Host, ":", "%3A")
+	webDID = "did:web:" + host + ":users:alice"
+
+	resolver := NewWebResolver(server.Client())
+
+	did, _ := ParseDID(webDID)
+	doc, rm, _, err := resolver.Resolve(context.Background(), did)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, webDID, doc.ID.String())
+	assert.Equal(t, ContentTypeDIDJSON, rm.ContentType)
+
+	did, _ = ParseDID("did:web:" + host + ":users:bob")
+	_, _, _, err = resolver.Resolve(context.Background(), did)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// oversized documents are rejected instead of being truncated
+	did, _ = ParseDID("did:web:" + host + ":users:large")
+	_, rm, _, err = resolver.Resolve(context.Background(), did)
+	assert.ErrorIs(t, err, ErrInvalidDIDDocument)
+	assert.Contains(t, err.Error(), "too large")
+	assert.Equal(t, ErrCodeInvalidDIDDocument, rm.Error)
+
+	// document served for a different DID must be rejected
+	did, _ = ParseDID("did:web:" + host + ":users:mallory")
+	_, rm, _, err = resolver.Resolve(context.Background(), did)
+	assert.ErrorIs(t, err, ErrInvalidDIDDocument)
+	assert.Equal(t, ErrCodeInvalidDIDDocument, rm.Error)
+
+	// server failures are reported in the resolution metadata
+	did, _ = ParseDID("did:web:" + host + ":users:broken")
+	_, rm, _, err = resolver.Resolve(context.Background(), did)
+	assert.ErrorIs(t, err, ErrInternalError)
+	assert.Contains(t, err.Error(), "unexpected status 503")
+	assert.Equal(t, ErrCodeInternalError, rm.Error)
+}
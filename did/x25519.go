@@ -0,0 +1,121 @@
+package did
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"fmt"
+	"math/big"
+
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+)
+
+var (
+	// field prime of curve25519: 2^255 - 19
+	curve25519P, _ = new(big.Int).SetString("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed", 16)
+	// edwards25519 curve constant d = -121665/121666
+	edwards25519D = new(big.Int).Mod(
+		new(big.Int).Mul(big.NewInt(-121665), new(big.Int).ModInverse(big.NewInt(121666), curve25519P)),
+		curve25519P,
+	)
+)
+
+// ed25519PublicKeyToX25519 converts an Ed25519 public key to the X25519 public key using the
+// birational map between edwards25519 and curve25519: u = (1 + y) / (1 - y)
+func ed25519PublicKeyToX25519(pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key size: %d", len(pub))
+	}
+	le := make([]byte, len(pub))
+	copy(le, pub)
+	le[31] &= 0x7f // clear the sign bit of x
+	y := new(big.Int).SetBytes(reverseBytes(le))
+	if y.Cmp(curve25519P) >= 0 {
+		return nil, fmt.Errorf("invalid ed25519 public key: y not reduced")
+	}
+
+	// the point is on the curve when x^2 = (y^2 - 1) / (d*y^2 + 1) has a solution
+	y2 := new(big.Int).Mul(y, y)
+	num := new(big.Int).Sub(y2, big.NewInt(1))
+	den := new(big.Int).Add(new(big.Int).Mul(edwards25519D, y2), big.NewInt(1))
+	den.Mod(den, curve25519P)
+	denInv := new(big.Int).ModInverse(den, curve25519P)
+	if denInv == nil {
+		return nil, fmt.Errorf("invalid ed25519 public key: not on curve")
+	}
+	x2 := new(big.Int).Mul(num, denInv)
+	x2.Mod(x2, curve25519P)
+	if x2.Sign() != 0 && big.Jacobi(x2, curve25519P) != 1 {
+		return nil, fmt.Errorf("invalid ed25519 public key: not on curve")
+	}
+
+	oneMinusY := new(big.Int).Sub(big.NewInt(1), y)
+	oneMinusY.Mod(oneMinusY, curve25519P)
+	inv := new(big.Int).ModInverse(oneMinusY, curve25519P)
+	if inv == nil {
+		return nil, fmt.Errorf("invalid ed25519 public key: identity point")
+	}
+	u := new(big.Int).Add(big.NewInt(1), y)
+	u.Mul(u, inv)
+	u.Mod(u, curve25519P)
+
+	out := make([]byte, 32)
+	u.FillBytes(out)
+	return reverseBytes(out), nil
+}
+
+// Ed25519PublicKeyToX25519 converts the Ed25519 public key to the X25519 key agreement key,
+// the same way did:key derives its keyAgreement method
+func Ed25519PublicKeyToX25519(pub ed25519.PublicKey) (*ecdh.PublicKey, error) {
+	raw, err := ed25519PublicKeyToX25519(pub)
+	if err != nil {
+		return nil, err
+	}
+	return ecdh.X25519().NewPublicKey(raw)
+}
+
+// Ed25519PrivateKeyToX25519 converts the Ed25519 private key to the X25519 private key of Ed25519PublicKeyToX25519.
+// The X25519 scalar is the first half of SHA-512(seed), X25519 clamps it the same way Ed25519 does.
+func Ed25519PrivateKeyToX25519(priv ed25519.PrivateKey) (*ecdh.PrivateKey, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key size: %d", len(priv))
+	}
+	h := sha512.Sum512(priv.Seed())
+	return ecdh.X25519().NewPrivateKey(h[:32])
+}
+
+func reverseBytes(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+// x25519PublicKey converts a key agreement key to *ecdh.PublicKey. Besides *ecdh.PublicKey
+// raw 32 byte keys (legacy keys stored in ed25519.PublicKey) are accepted.
+func x25519PublicKey(publicKey crypto.PublicKey) (*ecdh.PublicKey, error) {
+	switch k := publicKey.(type) {
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("%w: key agreement key must be x25519", ErrUnsupportedKeyType)
+		}
+		return k, nil
+	case ed25519.PublicKey:
+		return ecdh.X25519().NewPublicKey(k)
+	case []byte:
+		return ecdh.X25519().NewPublicKey(k)
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKeyType, publicKey)
+}
+
+// x25519PublicKeyToJwk wraps the X25519 key in an OKP/X25519 PublicKeyJwk
+func x25519PublicKeyToJwk(publicKey *ecdh.PublicKey) (*PublicKeyJwk, error) {
+	key, err := jwk.FromRaw(x25519.PublicKey(publicKey.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKeyJwk{Key: key}, nil
+}
@@ -0,0 +1,30 @@
+package did
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEd25519ToX25519(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	xPub, err := Ed25519PublicKeyToX25519(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xPriv, err := Ed25519PrivateKeyToX25519(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, xPriv.PublicKey().Equal(xPub))
+
+	_, other, _ := CreateX25519Keys()
+	sent, _ := other.ECDH(xPub)
+	received, _ := xPriv.ECDH(other.PublicKey())
+	assert.Equal(t, sent, received)
+
+	_, err = Ed25519PrivateKeyToX25519(priv[:32])
+	assert.Error(t, err)
+}